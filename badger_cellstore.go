@@ -0,0 +1,129 @@
+package xlsx
+
+import (
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+// BadgerCellStore is a CellStore backed by Badger, aimed at high-throughput
+// streaming writes: unlike BoltCellStore it doesn't buffer a row's worth of
+// cells before committing, since Badger's own LSM-tree write path and value
+// log are already designed to absorb many small, concurrent writes cheaply.
+type BadgerCellStore struct {
+	db    *badger.DB
+	codec *cellCodecPool
+}
+
+// NewBadgerCellStore opens (creating if necessary) a Badger-backed CellStore
+// at path.
+func NewBadgerCellStore(path string) (CellStore, error) {
+	opts := badger.DefaultOptions(path)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerCellStore{
+		db:    db,
+		codec: newCellCodecPool(),
+	}, nil
+}
+
+func (cs *BadgerCellStore) WriteCell(c *Cell) error {
+	s := cs.codec.get()
+	b, err := encodeCellV2(s, c)
+	cs.codec.put(s)
+	if err != nil {
+		return err
+	}
+
+	key := c.key()
+	return cs.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), b)
+	})
+}
+
+func (cs *BadgerCellStore) ReadCell(key string) (*Cell, error) {
+	var raw []byte
+	err := cs.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return NewCellNotFoundError(key, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			raw = append([]byte(nil), v...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := cs.codec.get()
+	defer cs.codec.put(s)
+	return decodeCellV2(s, raw)
+}
+
+func (cs *BadgerCellStore) DeleteCell(key string) error {
+	return cs.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (cs *BadgerCellStore) ForEach(cvf CellVisitorFunc) error {
+	return cs.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			var c *Cell
+			err := item.Value(func(v []byte) error {
+				s := cs.codec.get()
+				defer cs.codec.put(s)
+				var err error
+				c, err = decodeCellV2(s, v)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if err := cvf(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cs *BadgerCellStore) ForEachInRow(r *Row, cvf CellVisitorFunc) error {
+	pref := []byte(r.makeCellKeyRowPrefix())
+	return cs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = pref
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Seek(pref); it.ValidForPrefix(pref); it.Next() {
+			item := it.Item()
+			var c *Cell
+			err := item.Value(func(v []byte) error {
+				s := cs.codec.get()
+				defer cs.codec.put(s)
+				var err error
+				c, err = decodeCellV2(s, v)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if err := cvf(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cs *BadgerCellStore) Close() error {
+	return cs.db.Close()
+}
@@ -0,0 +1,192 @@
+package xlsx
+
+import (
+	"bytes"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var cellsBucket = []byte("cells")
+
+// BoltCellStore is a CellStore backed by a single bbolt file. Unlike
+// DiskVCellStore, which creates one file per cell, writes are buffered in
+// memory and committed in one bbolt write transaction per row: WriteCell
+// only flushes the previously buffered row once a cell from a different row
+// arrives, so a sheet written row-by-row (the common case) pays for one
+// transaction per row instead of one per cell.
+type BoltCellStore struct {
+	db    *bolt.DB
+	codec *cellCodecPool
+
+	mu         sync.Mutex
+	pendingRow string
+	pending    map[string][]byte
+}
+
+// NewBoltCellStore opens (creating if necessary) a bbolt-backed CellStore at
+// path.
+func NewBoltCellStore(path string) (CellStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(cellsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltCellStore{
+		db:      db,
+		codec:   newCellCodecPool(),
+		pending: make(map[string][]byte),
+	}, nil
+}
+
+func (cs *BoltCellStore) WriteCell(c *Cell) error {
+	key := c.key()
+
+	s := cs.codec.get()
+	b, err := encodeCellV2(s, c)
+	cs.codec.put(s)
+	if err != nil {
+		return err
+	}
+
+	rowPrefix := cellTransform(key)[0]
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.pendingRow != "" && cs.pendingRow != rowPrefix {
+		if err := cs.flushLocked(); err != nil {
+			return err
+		}
+	}
+	cs.pendingRow = rowPrefix
+	cs.pending[key] = b
+	return nil
+}
+
+// flushLocked commits all buffered writes in a single bbolt transaction. The
+// caller must hold cs.mu.
+func (cs *BoltCellStore) flushLocked() error {
+	if len(cs.pending) == 0 {
+		return nil
+	}
+	err := cs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(cellsBucket)
+		for k, v := range cs.pending {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cs.pending = make(map[string][]byte)
+	cs.pendingRow = ""
+	return nil
+}
+
+// Flush commits any buffered row that hasn't been flushed yet. Callers that
+// need to observe writes from another goroutine, or via the bbolt file
+// directly, should call this first.
+func (cs *BoltCellStore) Flush() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.flushLocked()
+}
+
+func (cs *BoltCellStore) ReadCell(key string) (*Cell, error) {
+	cs.mu.Lock()
+	if b, ok := cs.pending[key]; ok {
+		cs.mu.Unlock()
+		s := cs.codec.get()
+		defer cs.codec.put(s)
+		return decodeCellV2(s, b)
+	}
+	cs.mu.Unlock()
+
+	var raw []byte
+	err := cs.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(cellsBucket).Get([]byte(key))
+		if v == nil {
+			return NewCellNotFoundError(key, "not found in bolt cellstore")
+		}
+		raw = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := cs.codec.get()
+	defer cs.codec.put(s)
+	return decodeCellV2(s, raw)
+}
+
+func (cs *BoltCellStore) DeleteCell(key string) error {
+	cs.mu.Lock()
+	delete(cs.pending, key)
+	if err := cs.flushLocked(); err != nil {
+		cs.mu.Unlock()
+		return err
+	}
+	cs.mu.Unlock()
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cellsBucket).Delete([]byte(key))
+	})
+}
+
+func (cs *BoltCellStore) ForEach(cvf CellVisitorFunc) error {
+	if err := cs.Flush(); err != nil {
+		return err
+	}
+
+	return cs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(cellsBucket).ForEach(func(k, v []byte) error {
+			s := cs.codec.get()
+			c, err := decodeCellV2(s, v)
+			cs.codec.put(s)
+			if err != nil {
+				return err
+			}
+			return cvf(c)
+		})
+	})
+}
+
+func (cs *BoltCellStore) ForEachInRow(r *Row, cvf CellVisitorFunc) error {
+	if err := cs.Flush(); err != nil {
+		return err
+	}
+	pref := []byte(r.makeCellKeyRowPrefix())
+
+	return cs.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(cellsBucket).Cursor()
+		for k, v := cur.Seek(pref); k != nil && bytes.HasPrefix(k, pref); k, v = cur.Next() {
+			s := cs.codec.get()
+			c, err := decodeCellV2(s, v)
+			cs.codec.put(s)
+			if err != nil {
+				return err
+			}
+			if err := cvf(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cs *BoltCellStore) Close() error {
+	if err := cs.Flush(); err != nil {
+		return err
+	}
+	return cs.db.Close()
+}
@@ -0,0 +1,473 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements formula tokenizing and parsing for the calc
+// subsystem. Evaluation itself (calcEngine, CalcCellValue, Recalculate)
+// lives in calc_engine.go, and the built-in function registry lives in
+// calc_functions.go.
+
+// FormulaArgType identifies the kind of value a FormulaArg holds.
+type FormulaArgType int
+
+const (
+	ArgEmpty FormulaArgType = iota
+	ArgNumber
+	ArgString
+	ArgBool
+	ArgError
+	ArgRange
+)
+
+// FormulaArg is the value type formulas are evaluated to and functions
+// operate on: a small tagged union covering the scalar types Excel formulas
+// produce, plus ArgRange for a 2D block of cells passed to a function like
+// SUM or VLOOKUP.
+type FormulaArg struct {
+	Type      FormulaArgType
+	Number    float64
+	String    string
+	Bool      bool
+	Err       string // e.g. "#VALUE!", "#DIV/0!", "#CIRC!"
+	RangeVals [][]FormulaArg
+}
+
+func NewNumberArg(n float64) FormulaArg { return FormulaArg{Type: ArgNumber, Number: n} }
+func NewStringArg(s string) FormulaArg  { return FormulaArg{Type: ArgString, String: s} }
+func NewBoolArg(b bool) FormulaArg      { return FormulaArg{Type: ArgBool, Bool: b} }
+func NewErrorArg(e string) FormulaArg   { return FormulaArg{Type: ArgError, Err: e} }
+
+// ToNumber coerces a to a float64, the way Excel coerces operands of
+// arithmetic operators: numbers pass through, TRUE/FALSE become 1/0, and
+// numeric strings are parsed. ok is false if a can't be coerced.
+func (a FormulaArg) ToNumber() (float64, bool) {
+	switch a.Type {
+	case ArgNumber:
+		return a.Number, true
+	case ArgBool:
+		if a.Bool {
+			return 1, true
+		}
+		return 0, true
+	case ArgString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(a.String), 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// ToString renders a the way Excel renders a value used as text, e.g. by
+// CONCATENATE or "&".
+func (a FormulaArg) ToString() string {
+	switch a.Type {
+	case ArgString:
+		return a.String
+	case ArgNumber:
+		return strconv.FormatFloat(a.Number, 'f', -1, 64)
+	case ArgBool:
+		if a.Bool {
+			return "TRUE"
+		}
+		return "FALSE"
+	case ArgError:
+		return a.Err
+	}
+	return ""
+}
+
+// ToBool coerces a to a boolean, the way Excel coerces operands of AND/OR/NOT.
+func (a FormulaArg) ToBool() bool {
+	switch a.Type {
+	case ArgBool:
+		return a.Bool
+	case ArgNumber:
+		return a.Number != 0
+	case ArgString:
+		return strings.EqualFold(a.String, "TRUE")
+	}
+	return false
+}
+
+// cellRef is a parsed A1-style reference, 0-based, optionally qualified with
+// a sheet name (empty meaning "the sheet the formula lives on").
+type cellRef struct {
+	sheet string
+	col   int
+	row   int
+}
+
+// calcNodeType identifies the kind of node in a parsed formula's AST.
+type calcNodeType int
+
+const (
+	nodeNumber calcNodeType = iota
+	nodeString
+	nodeBool
+	nodeRef
+	nodeRange
+	nodeFuncCall
+	nodeBinOp
+	nodeUnaryOp
+)
+
+type calcNode struct {
+	typ      calcNodeType
+	number   float64
+	str      string
+	ref      cellRef
+	rangeEnd cellRef
+	fn       string
+	args     []*calcNode
+	op       string
+	left     *calcNode
+	right    *calcNode
+}
+
+// calcPrecedence gives the binding power of each binary operator; higher
+// binds tighter. Comparisons are lowest, concatenation next, then the usual
+// arithmetic ladder.
+var calcPrecedence = map[string]int{
+	"=": 1, "<>": 1, "<": 1, ">": 1, "<=": 1, ">=": 1,
+	"&": 2,
+	"+": 3, "-": 3,
+	"*": 4, "/": 4,
+	"^": 5,
+}
+
+type calcTokenKind int
+
+const (
+	tokEOF calcTokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokColon
+)
+
+type calcToken struct {
+	kind calcTokenKind
+	text string
+	num  float64
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '!' || c == '\''
+}
+
+// calcTokenize turns a formula body (with any leading "=" already stripped)
+// into a flat token stream.
+func calcTokenize(formula string) ([]calcToken, error) {
+	var toks []calcToken
+	i, n := 0, len(formula)
+	for i < n {
+		c := formula[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, calcToken{kind: tokLParen})
+			i++
+		case c == ')':
+			toks = append(toks, calcToken{kind: tokRParen})
+			i++
+		case c == ',':
+			toks = append(toks, calcToken{kind: tokComma})
+			i++
+		case c == ':':
+			toks = append(toks, calcToken{kind: tokColon})
+			i++
+		case c == '"':
+			j := i + 1
+			var sb strings.Builder
+			for j < n && formula[j] != '"' {
+				sb.WriteByte(formula[j])
+				j++
+			}
+			if j >= n {
+				return nil, errors.New("xlsx: unterminated string in formula")
+			}
+			toks = append(toks, calcToken{kind: tokString, text: sb.String()})
+			i = j + 1
+		case c == '<':
+			if i+1 < n && (formula[i+1] == '=' || formula[i+1] == '>') {
+				toks = append(toks, calcToken{kind: tokOp, text: formula[i : i+2]})
+				i += 2
+			} else {
+				toks = append(toks, calcToken{kind: tokOp, text: "<"})
+				i++
+			}
+		case c == '>':
+			if i+1 < n && formula[i+1] == '=' {
+				toks = append(toks, calcToken{kind: tokOp, text: ">="})
+				i += 2
+			} else {
+				toks = append(toks, calcToken{kind: tokOp, text: ">"})
+				i++
+			}
+		case c == '=' || c == '+' || c == '-' || c == '*' || c == '/' || c == '^' || c == '&':
+			toks = append(toks, calcToken{kind: tokOp, text: string(c)})
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < n && (formula[j] >= '0' && formula[j] <= '9' || formula[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(formula[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("xlsx: invalid number %q in formula", formula[i:j])
+			}
+			toks = append(toks, calcToken{kind: tokNumber, num: f})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < n && isIdentPart(formula[j]) {
+				j++
+			}
+			toks = append(toks, calcToken{kind: tokIdent, text: formula[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("xlsx: unexpected character %q in formula", c)
+		}
+	}
+	toks = append(toks, calcToken{kind: tokEOF})
+	return toks, nil
+}
+
+func parseCellRefToken(s string) (cellRef, error) {
+	sheet := ""
+	ref := s
+	if idx := strings.Index(s, "!"); idx >= 0 {
+		sheet = strings.Trim(s[:idx], "'")
+		ref = s[idx+1:]
+	}
+	ref = strings.ReplaceAll(ref, "$", "")
+	col, row, err := GetCoordsFromCellIDString(ref)
+	if err != nil {
+		return cellRef{}, fmt.Errorf("xlsx: invalid cell reference %q", s)
+	}
+	return cellRef{sheet: sheet, col: col, row: row}, nil
+}
+
+type calcParser struct {
+	toks []calcToken
+	pos  int
+}
+
+func (p *calcParser) peek() calcToken { return p.toks[p.pos] }
+
+func (p *calcParser) next() calcToken {
+	t := p.toks[p.pos]
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+// parseFormula parses an Excel formula (with or without a leading "=") into
+// an AST ready for calcEngine to evaluate.
+func parseFormula(formula string) (*calcNode, error) {
+	formula = strings.TrimPrefix(formula, "=")
+	toks, err := calcTokenize(formula)
+	if err != nil {
+		return nil, err
+	}
+	p := &calcParser{toks: toks}
+	node, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, errors.New("xlsx: trailing tokens in formula")
+	}
+	return node, nil
+}
+
+func (p *calcParser) parseExpr(minPrec int) (*calcNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp {
+			break
+		}
+		prec, ok := calcPrecedence[tok.text]
+		if !ok || prec < minPrec {
+			break
+		}
+		op := p.next().text
+		right, err := p.parseExpr(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &calcNode{typ: nodeBinOp, op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *calcParser) parseUnary() (*calcNode, error) {
+	tok := p.peek()
+	if tok.kind == tokOp && tok.text == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &calcNode{typ: nodeUnaryOp, op: "-", left: operand}, nil
+	}
+	if tok.kind == tokOp && tok.text == "+" {
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *calcParser) parsePrimary() (*calcNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &calcNode{typ: nodeNumber, number: tok.num}, nil
+	case tokString:
+		return &calcNode{typ: nodeString, str: tok.text}, nil
+	case tokLParen:
+		node, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, errors.New("xlsx: expected ) in formula")
+		}
+		p.next()
+		return node, nil
+	case tokIdent:
+		name := tok.text
+		if strings.EqualFold(name, "TRUE") {
+			return &calcNode{typ: nodeBool, number: 1}, nil
+		}
+		if strings.EqualFold(name, "FALSE") {
+			return &calcNode{typ: nodeBool, number: 0}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseFuncCall(name)
+		}
+		ref, err := parseCellRefToken(name)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind == tokColon {
+			p.next()
+			endTok := p.next()
+			if endTok.kind != tokIdent {
+				return nil, errors.New("xlsx: expected range end reference in formula")
+			}
+			endRef, err := parseCellRefToken(endTok.text)
+			if err != nil {
+				return nil, err
+			}
+			return &calcNode{typ: nodeRange, ref: ref, rangeEnd: endRef}, nil
+		}
+		return &calcNode{typ: nodeRef, ref: ref}, nil
+	}
+	return nil, errors.New("xlsx: unexpected token in formula")
+}
+
+func (p *calcParser) parseFuncCall(name string) (*calcNode, error) {
+	p.next() // consume '('
+	var args []*calcNode
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind == tokComma {
+				p.next()
+				continue
+			}
+			break
+		}
+	}
+	if p.peek().kind != tokRParen {
+		return nil, fmt.Errorf("xlsx: expected ) after arguments to %s", name)
+	}
+	p.next()
+	return &calcNode{typ: nodeFuncCall, fn: strings.ToUpper(name), args: args}, nil
+}
+
+// ParseR1C1Ref parses an R1C1-style reference such as "R2C3" (absolute) or
+// "R[1]C[-1]" (relative) into the 0-based (col, row) it designates, relative
+// to the current cell at (curRow, curCol).
+func ParseR1C1Ref(s string, curRow, curCol int) (col, row int, err error) {
+	s = strings.ToUpper(s)
+	if len(s) == 0 || s[0] != 'R' {
+		return 0, 0, fmt.Errorf("xlsx: invalid R1C1 reference %q", s)
+	}
+	r, i, err := parseR1C1Component(s, 1, curRow)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i >= len(s) || s[i] != 'C' {
+		return 0, 0, fmt.Errorf("xlsx: invalid R1C1 reference %q", s)
+	}
+	c, i, err := parseR1C1Component(s, i+1, curCol)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i != len(s) {
+		return 0, 0, fmt.Errorf("xlsx: invalid R1C1 reference %q", s)
+	}
+	return c, r, nil
+}
+
+// parseR1C1Component parses the numeric part of a single R or C component
+// starting at index i, returning the 0-based absolute coordinate and the
+// index just past what it consumed.
+func parseR1C1Component(s string, i, cur int) (val, next int, err error) {
+	if i >= len(s) || s[i] == 'R' || s[i] == 'C' {
+		return cur, i, nil // bare "R"/"C": current row/col
+	}
+	if s[i] == '[' {
+		j := i + 1
+		for j < len(s) && s[j] != ']' {
+			j++
+		}
+		if j >= len(s) {
+			return 0, 0, errors.New("xlsx: unterminated [ in R1C1 reference")
+		}
+		n, err := strconv.Atoi(s[i+1 : j])
+		if err != nil {
+			return 0, 0, err
+		}
+		return cur + n, j + 1, nil
+	}
+	j := i
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+	}
+	if j == i {
+		return cur, j, nil
+	}
+	n, err := strconv.Atoi(s[i:j])
+	if err != nil {
+		return 0, 0, err
+	}
+	return n - 1, j, nil // absolute R1C1 indices are 1-based
+}
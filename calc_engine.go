@@ -0,0 +1,373 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// calcState tracks a cell's position in the dependency DAG walk: unvisited
+// cells haven't been reached yet, in-progress cells are on the current
+// recursion stack (reaching one again means a cycle), and done cells have a
+// cached result.
+type calcState int
+
+const (
+	calcStateUnvisited calcState = iota
+	calcStateInProgress
+	calcStateDone
+)
+
+// calcEngine evaluates formulas for a single CalcCellValue or Recalculate
+// call. It walks the dependency graph implied by cell references via plain
+// recursion, memoizing each cell's result and using calcState to detect
+// circular references (reported as the #CIRC! error value) instead of
+// re-entering them.
+type calcEngine struct {
+	file     *File
+	date1904 bool
+	state    map[string]calcState
+	results  map[string]FormulaArg
+}
+
+func newCalcEngine(f *File) *calcEngine {
+	e := &calcEngine{
+		state:   make(map[string]calcState),
+		results: make(map[string]FormulaArg),
+		file:    f,
+	}
+	if f != nil {
+		e.date1904 = f.Date1904
+	}
+	return e
+}
+
+func calcCellKey(sheet *Sheet, row, col int) string {
+	return fmt.Sprintf("%p:%d:%d", sheet, row, col)
+}
+
+// CalcCellValue evaluates the formula in the cell at axis (e.g. "B2") on the
+// named sheet, writes the result back into the cell's Value (formula is left
+// untouched), and returns the result as a string. Cells without a formula
+// simply return their existing Value.
+func (f *File) CalcCellValue(sheetName, axis string) (string, error) {
+	sheet, ok := f.Sheet[sheetName]
+	if !ok {
+		return "", fmt.Errorf("xlsx: sheet %q not found", sheetName)
+	}
+	col, row, err := GetCoordsFromCellIDString(axis)
+	if err != nil {
+		return "", err
+	}
+
+	cell := sheet.Cell(row, col)
+	if cell == nil {
+		return "", nil
+	}
+	if cell.formula == "" {
+		return cell.Value, nil
+	}
+
+	e := newCalcEngine(f)
+	result := e.evalCellKey(sheet, row, col)
+	cell.Value = formulaArgToCellString(result)
+	return formulaArgToCellString(result), nil
+}
+
+// Recalculate re-evaluates every formula cell in the sheet, in dependency
+// order, writing each result back into Cell.Value while leaving Cell.formula
+// untouched. A single calcEngine (and so a single memoization/cycle-checking
+// pass) is shared across the whole sheet.
+func (s *Sheet) Recalculate() error {
+	e := newCalcEngine(s.File)
+	for rowIdx, row := range s.Rows {
+		if row == nil {
+			continue
+		}
+		for colIdx, cell := range row.Cells {
+			if cell == nil || cell.formula == "" {
+				continue
+			}
+			result := e.evalCellKey(s, rowIdx, colIdx)
+			cell.Value = formulaArgToCellString(result)
+		}
+	}
+	return nil
+}
+
+func (e *calcEngine) evalCellKey(sheet *Sheet, row, col int) FormulaArg {
+	key := calcCellKey(sheet, row, col)
+	switch e.state[key] {
+	case calcStateDone:
+		return e.results[key]
+	case calcStateInProgress:
+		return NewErrorArg("#CIRC!")
+	}
+	e.state[key] = calcStateInProgress
+
+	var result FormulaArg
+	cell := sheet.Cell(row, col)
+	if cell == nil || cell.formula == "" {
+		result = cellLiteralArg(cell)
+	} else if node, err := parseFormula(cell.formula); err != nil {
+		result = NewErrorArg("#NAME?")
+	} else {
+		result = e.evalNode(sheet, node)
+	}
+
+	e.state[key] = calcStateDone
+	e.results[key] = result
+	return result
+}
+
+func (e *calcEngine) resolveSheet(current *Sheet, name string) *Sheet {
+	if name == "" {
+		return current
+	}
+	if e.file == nil {
+		return nil
+	}
+	sheet, ok := e.file.Sheet[name]
+	if !ok {
+		return nil
+	}
+	return sheet
+}
+
+func (e *calcEngine) evalNode(sheet *Sheet, node *calcNode) FormulaArg {
+	switch node.typ {
+	case nodeNumber:
+		return NewNumberArg(node.number)
+	case nodeString:
+		return NewStringArg(node.str)
+	case nodeBool:
+		return NewBoolArg(node.number != 0)
+	case nodeUnaryOp:
+		v := e.evalNode(sheet, node.left)
+		if v.Type == ArgError {
+			return v
+		}
+		n, ok := v.ToNumber()
+		if !ok {
+			return NewErrorArg("#VALUE!")
+		}
+		return NewNumberArg(-n)
+	case nodeBinOp:
+		return e.evalBinOp(sheet, node)
+	case nodeRef:
+		target := e.resolveSheet(sheet, node.ref.sheet)
+		if target == nil {
+			return NewErrorArg("#REF!")
+		}
+		return e.evalCellKey(target, node.ref.row, node.ref.col)
+	case nodeRange:
+		// A bare range with no enclosing function (e.g. "=A1:A3") has no
+		// single scalar value.
+		return NewErrorArg("#VALUE!")
+	case nodeFuncCall:
+		return e.evalFuncCall(sheet, node)
+	}
+	return NewErrorArg("#ERROR!")
+}
+
+func (e *calcEngine) evalBinOp(sheet *Sheet, node *calcNode) FormulaArg {
+	left := e.evalNode(sheet, node.left)
+	if left.Type == ArgError {
+		return left
+	}
+	right := e.evalNode(sheet, node.right)
+	if right.Type == ArgError {
+		return right
+	}
+
+	if node.op == "&" {
+		return NewStringArg(left.ToString() + right.ToString())
+	}
+	if _, ok := calcComparisonOps[node.op]; ok {
+		return NewBoolArg(compareArgs(left, right, node.op))
+	}
+
+	ln, lok := left.ToNumber()
+	rn, rok := right.ToNumber()
+	if !lok || !rok {
+		return NewErrorArg("#VALUE!")
+	}
+	switch node.op {
+	case "+":
+		return NewNumberArg(ln + rn)
+	case "-":
+		return NewNumberArg(ln - rn)
+	case "*":
+		return NewNumberArg(ln * rn)
+	case "/":
+		if rn == 0 {
+			return NewErrorArg("#DIV/0!")
+		}
+		return NewNumberArg(ln / rn)
+	case "^":
+		return NewNumberArg(math.Pow(ln, rn))
+	}
+	return NewErrorArg("#ERROR!")
+}
+
+var calcComparisonOps = map[string]bool{
+	"=": true, "<>": true, "<": true, ">": true, "<=": true, ">=": true,
+}
+
+func compareArgs(l, r FormulaArg, op string) bool {
+	if ln, lok := l.ToNumber(); lok {
+		if rn, rok := r.ToNumber(); rok {
+			switch op {
+			case "=":
+				return ln == rn
+			case "<>":
+				return ln != rn
+			case "<":
+				return ln < rn
+			case ">":
+				return ln > rn
+			case "<=":
+				return ln <= rn
+			case ">=":
+				return ln >= rn
+			}
+			return false
+		}
+	}
+	ls, rs := l.ToString(), r.ToString()
+	switch op {
+	case "=":
+		return ls == rs
+	case "<>":
+		return ls != rs
+	case "<":
+		return ls < rs
+	case ">":
+		return ls > rs
+	case "<=":
+		return ls <= rs
+	case ">=":
+		return ls >= rs
+	}
+	return false
+}
+
+func (e *calcEngine) evalRange(sheet *Sheet, node *calcNode) FormulaArg {
+	target := e.resolveSheet(sheet, node.ref.sheet)
+	if target == nil {
+		return NewErrorArg("#REF!")
+	}
+	r0, r1 := node.ref.row, node.rangeEnd.row
+	if r0 > r1 {
+		r0, r1 = r1, r0
+	}
+	c0, c1 := node.ref.col, node.rangeEnd.col
+	if c0 > c1 {
+		c0, c1 = c1, c0
+	}
+
+	grid := make([][]FormulaArg, 0, r1-r0+1)
+	for row := r0; row <= r1; row++ {
+		rowVals := make([]FormulaArg, 0, c1-c0+1)
+		for col := c0; col <= c1; col++ {
+			rowVals = append(rowVals, e.evalCellKey(target, row, col))
+		}
+		grid = append(grid, rowVals)
+	}
+	return FormulaArg{Type: ArgRange, RangeVals: grid}
+}
+
+func (e *calcEngine) evalFuncCall(sheet *Sheet, node *calcNode) FormulaArg {
+	// DATE is handled directly by the engine rather than through the
+	// RegisterFunc registry because it needs the workbook's date1904 flag,
+	// which a plain func(args []FormulaArg) FormulaArg has no way to see.
+	if node.fn == "DATE" {
+		return e.evalDate(sheet, node)
+	}
+
+	fn, ok := lookupFormulaFunc(node.fn)
+	if !ok {
+		return NewErrorArg("#NAME?")
+	}
+
+	args := make([]FormulaArg, len(node.args))
+	for i, a := range node.args {
+		var v FormulaArg
+		if a.typ == nodeRange {
+			v = e.evalRange(sheet, a)
+		} else {
+			v = e.evalNode(sheet, a)
+		}
+		if v.Type == ArgError {
+			return v
+		}
+		args[i] = v
+	}
+	return fn(args)
+}
+
+func (e *calcEngine) evalDate(sheet *Sheet, node *calcNode) FormulaArg {
+	if len(node.args) != 3 {
+		return NewErrorArg("#VALUE!")
+	}
+	y := e.evalNode(sheet, node.args[0])
+	m := e.evalNode(sheet, node.args[1])
+	d := e.evalNode(sheet, node.args[2])
+	if y.Type == ArgError {
+		return y
+	}
+	if m.Type == ArgError {
+		return m
+	}
+	if d.Type == ArgError {
+		return d
+	}
+	yn, ok1 := y.ToNumber()
+	mn, ok2 := m.ToNumber()
+	dn, ok3 := d.ToNumber()
+	if !ok1 || !ok2 || !ok3 {
+		return NewErrorArg("#VALUE!")
+	}
+	t := time.Date(int(yn), time.Month(int(mn)), int(dn), 0, 0, 0, 0, time.UTC)
+	return NewNumberArg(dateToSerial(t, e.date1904))
+}
+
+// excelEpoch returns the epoch serial date 0 represents for the given
+// date1904 flag, matching Excel's two date systems.
+func excelEpoch(date1904 bool) time.Time {
+	if date1904 {
+		return time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	// Excel's 1900 system treats 1900 as a leap year (a bug inherited from
+	// Lotus 1-2-3, kept for compatibility), making day 60 the fictitious Feb
+	// 29 1900. Anchoring on Dec 30 1899 instead of Jan 1 1900 absorbs that
+	// off-by-one for every date from Mar 1 1900 on; dateToSerial corrects the
+	// dates before it.
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+}
+
+func dateToSerial(t time.Time, date1904 bool) float64 {
+	serial := t.Sub(excelEpoch(date1904)).Hours() / 24
+	if !date1904 && t.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		serial-- // before the fictitious Feb 29 1900, serials run one lower
+	}
+	return serial
+}
+
+func cellLiteralArg(cell *Cell) FormulaArg {
+	if cell == nil {
+		return FormulaArg{Type: ArgEmpty}
+	}
+	if n, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+		return NewNumberArg(n)
+	}
+	return NewStringArg(cell.Value)
+}
+
+func formulaArgToCellString(a FormulaArg) string {
+	if a.Type == ArgError {
+		return a.Err
+	}
+	return a.ToString()
+}
@@ -0,0 +1,290 @@
+package xlsx
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FormulaFunc is the signature of a formula function: it receives its
+// already-evaluated arguments (ArgRange for range arguments such as
+// SUM(A1:A10)'s first argument) and returns a single result.
+type FormulaFunc func(args []FormulaArg) FormulaArg
+
+var (
+	formulaFuncsMu sync.RWMutex
+	formulaFuncs   = map[string]FormulaFunc{}
+)
+
+// RegisterFunc adds fn to the formula function registry under name
+// (case-insensitive), so it can be called from any formula evaluated by
+// CalcCellValue/Recalculate. Registering a name that already exists
+// (including a built-in) replaces it.
+func RegisterFunc(name string, fn FormulaFunc) {
+	formulaFuncsMu.Lock()
+	defer formulaFuncsMu.Unlock()
+	formulaFuncs[strings.ToUpper(name)] = fn
+}
+
+func lookupFormulaFunc(name string) (FormulaFunc, bool) {
+	formulaFuncsMu.RLock()
+	defer formulaFuncsMu.RUnlock()
+	fn, ok := formulaFuncs[name]
+	return fn, ok
+}
+
+func init() {
+	RegisterFunc("SUM", fnSUM)
+	RegisterFunc("AVERAGE", fnAVERAGE)
+	RegisterFunc("COUNTIF", fnCOUNTIF)
+	RegisterFunc("CONCATENATE", fnCONCATENATE)
+	RegisterFunc("LEFT", fnLEFT)
+	RegisterFunc("MID", fnMID)
+	RegisterFunc("AND", fnAND)
+	RegisterFunc("OR", fnOR)
+	RegisterFunc("NOT", fnNOT)
+	RegisterFunc("VLOOKUP", fnVLOOKUP)
+	RegisterFunc("INDEX", fnINDEX)
+	RegisterFunc("MATCH", fnMATCH)
+}
+
+// flattenArgs expands any ArgRange arguments into their constituent cells,
+// in row-major order, alongside the scalar arguments.
+func flattenArgs(args []FormulaArg) []FormulaArg {
+	var out []FormulaArg
+	for _, a := range args {
+		if a.Type == ArgRange {
+			for _, row := range a.RangeVals {
+				out = append(out, row...)
+			}
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func fnSUM(args []FormulaArg) FormulaArg {
+	var sum float64
+	for _, a := range flattenArgs(args) {
+		if n, ok := a.ToNumber(); ok {
+			sum += n
+		}
+	}
+	return NewNumberArg(sum)
+}
+
+func fnAVERAGE(args []FormulaArg) FormulaArg {
+	var sum float64
+	var count int
+	for _, a := range flattenArgs(args) {
+		if n, ok := a.ToNumber(); ok {
+			sum += n
+			count++
+		}
+	}
+	if count == 0 {
+		return NewErrorArg("#DIV/0!")
+	}
+	return NewNumberArg(sum / float64(count))
+}
+
+// fnCOUNTIF implements COUNTIF(range, criteria). criteria may be a bare
+// value (matched for equality) or a string starting with a comparison
+// operator, e.g. ">10".
+func fnCOUNTIF(args []FormulaArg) FormulaArg {
+	if len(args) != 2 {
+		return NewErrorArg("#VALUE!")
+	}
+	criteria := args[1]
+	count := 0
+	for _, a := range flattenArgs(args[:1]) {
+		if matchesCriteria(a, criteria) {
+			count++
+		}
+	}
+	return NewNumberArg(float64(count))
+}
+
+func matchesCriteria(v, criteria FormulaArg) bool {
+	crit := criteria.ToString()
+	op := "="
+	for _, candidate := range []string{"<=", ">=", "<>", "<", ">", "="} {
+		if strings.HasPrefix(crit, candidate) {
+			op = candidate
+			crit = strings.TrimPrefix(crit, candidate)
+			break
+		}
+	}
+	if n, ok := v.ToNumber(); ok {
+		if cn, err := strconv.ParseFloat(crit, 64); err == nil {
+			switch op {
+			case "=":
+				return n == cn
+			case "<>":
+				return n != cn
+			case "<":
+				return n < cn
+			case ">":
+				return n > cn
+			case "<=":
+				return n <= cn
+			case ">=":
+				return n >= cn
+			}
+		}
+	}
+	return op == "=" && strings.EqualFold(v.ToString(), crit)
+}
+
+func fnCONCATENATE(args []FormulaArg) FormulaArg {
+	var sb strings.Builder
+	for _, a := range args {
+		sb.WriteString(a.ToString())
+	}
+	return NewStringArg(sb.String())
+}
+
+// fnLEFT implements LEFT(text, [numChars]); numChars defaults to 1.
+func fnLEFT(args []FormulaArg) FormulaArg {
+	if len(args) == 0 {
+		return NewErrorArg("#VALUE!")
+	}
+	r := []rune(args[0].ToString())
+	n := 1
+	if len(args) > 1 {
+		if f, ok := args[1].ToNumber(); ok {
+			n = int(f)
+		}
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n > len(r) {
+		n = len(r)
+	}
+	return NewStringArg(string(r[:n]))
+}
+
+// fnMID implements MID(text, startNum, numChars), with startNum 1-based.
+func fnMID(args []FormulaArg) FormulaArg {
+	if len(args) != 3 {
+		return NewErrorArg("#VALUE!")
+	}
+	r := []rune(args[0].ToString())
+	start, ok1 := args[1].ToNumber()
+	count, ok2 := args[2].ToNumber()
+	if !ok1 || !ok2 {
+		return NewErrorArg("#VALUE!")
+	}
+	startIdx := int(start) - 1
+	if startIdx < 0 || startIdx >= len(r) {
+		return NewStringArg("")
+	}
+	end := startIdx + int(count)
+	if end > len(r) {
+		end = len(r)
+	}
+	return NewStringArg(string(r[startIdx:end]))
+}
+
+func fnAND(args []FormulaArg) FormulaArg {
+	for _, a := range flattenArgs(args) {
+		if !a.ToBool() {
+			return NewBoolArg(false)
+		}
+	}
+	return NewBoolArg(true)
+}
+
+func fnOR(args []FormulaArg) FormulaArg {
+	for _, a := range flattenArgs(args) {
+		if a.ToBool() {
+			return NewBoolArg(true)
+		}
+	}
+	return NewBoolArg(false)
+}
+
+func fnNOT(args []FormulaArg) FormulaArg {
+	if len(args) != 1 {
+		return NewErrorArg("#VALUE!")
+	}
+	return NewBoolArg(!args[0].ToBool())
+}
+
+func argsEqual(a, b FormulaArg) bool {
+	if an, aok := a.ToNumber(); aok {
+		if bn, bok := b.ToNumber(); bok {
+			return an == bn
+		}
+	}
+	return strings.EqualFold(a.ToString(), b.ToString())
+}
+
+// fnINDEX implements INDEX(range, rowNum, [colNum]), both 1-based.
+func fnINDEX(args []FormulaArg) FormulaArg {
+	if len(args) < 2 || args[0].Type != ArgRange {
+		return NewErrorArg("#VALUE!")
+	}
+	rng := args[0]
+	rowNum, ok := args[1].ToNumber()
+	if !ok {
+		return NewErrorArg("#VALUE!")
+	}
+	colNum := 1.0
+	if len(args) > 2 {
+		if c, ok := args[2].ToNumber(); ok {
+			colNum = c
+		}
+	}
+	r, c := int(rowNum)-1, int(colNum)-1
+	if r < 0 || r >= len(rng.RangeVals) || c < 0 || c >= len(rng.RangeVals[r]) {
+		return NewErrorArg("#REF!")
+	}
+	return rng.RangeVals[r][c]
+}
+
+// fnMATCH implements MATCH(value, range); it always does an exact match,
+// returning the 1-based position of the first cell equal to value.
+func fnMATCH(args []FormulaArg) FormulaArg {
+	if len(args) < 2 || args[1].Type != ArgRange {
+		return NewErrorArg("#VALUE!")
+	}
+	target := args[0]
+	for i, v := range flattenArgs(args[1:2]) {
+		if argsEqual(v, target) {
+			return NewNumberArg(float64(i + 1))
+		}
+	}
+	return NewErrorArg("#N/A")
+}
+
+// fnVLOOKUP implements VLOOKUP(value, range, colIndex): an exact-match
+// lookup of value in range's first column, returning the cell at colIndex
+// (1-based) of the matching row. Approximate (sorted range) matching is not
+// implemented.
+func fnVLOOKUP(args []FormulaArg) FormulaArg {
+	if len(args) < 3 || args[1].Type != ArgRange {
+		return NewErrorArg("#VALUE!")
+	}
+	target := args[0]
+	rng := args[1]
+	colIdx, ok := args[2].ToNumber()
+	if !ok {
+		return NewErrorArg("#VALUE!")
+	}
+	c := int(colIdx) - 1
+	for _, row := range rng.RangeVals {
+		if len(row) == 0 {
+			continue
+		}
+		if argsEqual(row[0], target) {
+			if c < 0 || c >= len(row) {
+				return NewErrorArg("#REF!")
+			}
+			return row[c]
+		}
+	}
+	return NewErrorArg("#N/A")
+}
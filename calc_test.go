@@ -0,0 +1,145 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func setFormula(sheet *Sheet, axis, formula string) {
+	col, row, _ := GetCoordsFromCellIDString(axis)
+	for len(sheet.Rows) <= row {
+		sheet.AddRow()
+	}
+	r := sheet.Rows[row]
+	for len(r.Cells) <= col {
+		r.AddCell()
+	}
+	r.Cells[col].formula = formula
+}
+
+func TestCalcCellValueArithmeticAndFunctions(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	setFormula(sheet, "A1", "=1+2*3")
+	setFormula(sheet, "A2", "=SUM(1,2,3)")
+	setFormula(sheet, "A3", `=CONCATENATE("foo", "bar")`)
+
+	v, err := file.CalcCellValue("Sheet1", "A1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "7")
+
+	v, err = file.CalcCellValue("Sheet1", "A2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "6")
+
+	v, err = file.CalcCellValue("Sheet1", "A3")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "foobar")
+}
+
+func TestCalcCellValueReferencesAndRanges(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	row1 := sheet.AddRow()
+	row1.AddCell().Value = "10"
+	row1.AddCell().Value = "20"
+	row1.AddCell().Value = "30"
+	setFormula(sheet, "A2", "=SUM(A1:C1)")
+	setFormula(sheet, "A3", "=A2/2")
+
+	v, err := file.CalcCellValue("Sheet1", "A2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "60")
+
+	v, err = file.CalcCellValue("Sheet1", "A3")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "30")
+}
+
+func TestCalcCellValueCircularReference(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	setFormula(sheet, "A1", "=A2+1")
+	setFormula(sheet, "A2", "=A1+1")
+
+	v, err := file.CalcCellValue("Sheet1", "A1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "#CIRC!")
+}
+
+func TestSheetRecalculate(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	row1 := sheet.AddRow()
+	row1.AddCell().Value = "4"
+	setFormula(sheet, "A2", "=A1*2")
+	setFormula(sheet, "A3", "=A2+A1")
+
+	c.Assert(sheet.Recalculate(), qt.IsNil)
+	c.Assert(sheet.Rows[1].Cells[0].Value, qt.Equals, "8")
+	c.Assert(sheet.Rows[2].Cells[0].Value, qt.Equals, "12")
+}
+
+func TestRegisterFuncCustom(t *testing.T) {
+	c := qt.New(t)
+
+	RegisterFunc("DOUBLEIT", func(args []FormulaArg) FormulaArg {
+		n, ok := args[0].ToNumber()
+		if !ok {
+			return NewErrorArg("#VALUE!")
+		}
+		return NewNumberArg(n * 2)
+	})
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+	setFormula(sheet, "A1", "=DOUBLEIT(21)")
+
+	v, err := file.CalcCellValue("Sheet1", "A1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "42")
+}
+
+func TestCalcCellValueVLOOKUPAndMatch(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	row1 := sheet.AddRow()
+	row1.AddCell().Value = "key1"
+	row1.AddCell().Value = "100"
+	row2 := sheet.AddRow()
+	row2.AddCell().Value = "key2"
+	row2.AddCell().Value = "200"
+
+	setFormula(sheet, "A3", `=VLOOKUP("key2", A1:B2, 2)`)
+	setFormula(sheet, "A4", `=MATCH("key2", A1:A2)`)
+
+	v, err := file.CalcCellValue("Sheet1", "A3")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "200")
+
+	v, err = file.CalcCellValue("Sheet1", "A4")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "2")
+}
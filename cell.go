@@ -0,0 +1,30 @@
+package xlsx
+
+import "strconv"
+
+// Cell is a single spreadsheet cell. Only Row and num locate it within its
+// sheet; everything else is the cell's content and formatting, and is what
+// CellStore implementations persist under the key derived from Row and num.
+type Cell struct {
+	Row *Row
+	num int // 0-based column index within Row
+
+	Value          string
+	formula        string
+	style          *Style
+	NumFmt         string
+	date1904       bool
+	Hidden         bool
+	HMerge         int
+	VMerge         int
+	cellType       CellType
+	DataValidation *xlsxDataValidation
+	Hyperlink      Hyperlink
+}
+
+// key returns the string a CellStore indexes this cell under: its row's
+// prefix (see Row.makeCellKeyRowPrefix) followed by its own column index, so
+// every cell in a row shares a sortable, iterable prefix.
+func (c *Cell) key() string {
+	return strconv.Itoa(c.Row.num) + ":" + strconv.Itoa(c.num)
+}
@@ -0,0 +1,99 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CellVisitorFunc is called once per cell by CellStore.ForEach and
+// CellStore.ForEachInRow.
+type CellVisitorFunc func(*Cell) error
+
+// CellStore persists the cells of a single sheet. Implementations trade off
+// memory footprint, durability and iteration cost differently (see
+// DiskVCellStore, BoltCellStore, BadgerCellStore, LRUCellStore,
+// MemoryCellStore), but all of them key cells by Cell.key() and must be safe
+// for concurrent use by multiple goroutines.
+type CellStore interface {
+	WriteCell(c *Cell) error
+	ReadCell(key string) (*Cell, error)
+	DeleteCell(key string) error
+	ForEach(cvf CellVisitorFunc) error
+	ForEachInRow(r *Row, cvf CellVisitorFunc) error
+	Close() error
+}
+
+// CellNotFoundError is returned by CellStore.ReadCell when key has no
+// corresponding cell, distinguishing "never written" from other I/O or
+// decoding failures.
+type CellNotFoundError struct {
+	Key    string
+	Reason string
+}
+
+// NewCellNotFoundError returns a CellNotFoundError for key, wrapping reason
+// as context (typically the underlying error's message).
+func NewCellNotFoundError(key, reason string) *CellNotFoundError {
+	return &CellNotFoundError{Key: key, Reason: reason}
+}
+
+func (e *CellNotFoundError) Error() string {
+	return fmt.Sprintf("xlsx: cell %q not found: %s", e.Key, e.Reason)
+}
+
+// CellType identifies the kind of value a Cell holds (string, numeric, bool,
+// error, ...), as read off the raw ST_CellType attribute of the source XML.
+type CellType int
+
+// GetCoordsFromCellIDString parses a cell reference such as "B2" (column
+// letters followed by a 1-based row number) into 0-based column and row
+// indices.
+func GetCoordsFromCellIDString(cellIDString string) (col, row int, err error) {
+	i := 0
+	for i < len(cellIDString) && isColumnLetter(cellIDString[i]) {
+		i++
+	}
+	if i == 0 || i == len(cellIDString) {
+		return 0, 0, fmt.Errorf("xlsx: invalid cell reference %q", cellIDString)
+	}
+	col = lettersToColIndex(cellIDString[:i])
+
+	rowPart := cellIDString[i:]
+	rowNum := 0
+	for _, r := range rowPart {
+		if r < '0' || r > '9' {
+			return 0, 0, fmt.Errorf("xlsx: invalid cell reference %q", cellIDString)
+		}
+		rowNum = rowNum*10 + int(r-'0')
+	}
+	if rowNum == 0 {
+		return 0, 0, fmt.Errorf("xlsx: invalid cell reference %q", cellIDString)
+	}
+	return col, rowNum - 1, nil
+}
+
+func isColumnLetter(b byte) bool {
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')
+}
+
+func lettersToColIndex(letters string) int {
+	col := 0
+	for _, r := range strings.ToUpper(letters) {
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// ColIndexToLetters converts a 0-based column index into its spreadsheet
+// column letters (0 -> "A", 25 -> "Z", 26 -> "AA", ...), the inverse of the
+// column portion of GetCoordsFromCellIDString.
+func ColIndexToLetters(col int) string {
+	col++ // switch to 1-based for the standard bijective base-26 conversion
+	var letters []byte
+	for col > 0 {
+		col--
+		letters = append([]byte{byte('A' + col%26)}, letters...)
+		col /= 26
+	}
+	return string(letters)
+}
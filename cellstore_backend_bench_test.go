@@ -0,0 +1,62 @@
+package xlsx
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// These benchmarks compare the CellStore backends against one another for a
+// large, sequential write workload (run with e.g. -benchtime=1000000x for
+// 1M cells). b.N cells are written to a single row, so this is a 1M-cell,
+// not a 1M-row, workload; b.ReportAllocs surfaces the allocation cost per
+// backend alongside go test's own reported ns/op.
+func benchmarkCellStoreBackendWrite(b *testing.B, factory CellStoreFactory) {
+	csIface, err := factory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	cs := csIface
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Bench")
+	row := sheet.AddRow()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cell := row.AddCell()
+		cell.Value = "value-" + strconv.Itoa(i)
+		if err := cs.WriteCell(cell); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCellStoreBackendWriteDiskV(b *testing.B) {
+	benchmarkCellStoreBackendWrite(b, DefaultCellStoreFactory)
+}
+
+func BenchmarkCellStoreBackendWriteBolt(b *testing.B) {
+	dir, err := ioutil.TempDir("", "cellstore-bolt-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	benchmarkCellStoreBackendWrite(b, NewBoltCellStoreFactory())
+}
+
+func BenchmarkCellStoreBackendWriteBadger(b *testing.B) {
+	dir, err := ioutil.TempDir("", "cellstore-badger-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	benchmarkCellStoreBackendWrite(b, NewBadgerCellStoreFactory())
+}
+
+func BenchmarkCellStoreBackendWriteLRUOverDiskV(b *testing.B) {
+	benchmarkCellStoreBackendWrite(b, NewLRUCellStoreFactory(1024, DefaultCellStoreFactory))
+}
@@ -0,0 +1,71 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"sync"
+)
+
+// cellCodecPool is a sync.Pool of diskVScratch buffers shared by CellStore
+// backends that reuse the FormatV2 codec (BoltCellStore, BadgerCellStore)
+// without paying diskv's own per-instance buffer cost.
+type cellCodecPool struct {
+	pool sync.Pool
+}
+
+func newCellCodecPool() *cellCodecPool {
+	cp := &cellCodecPool{}
+	cp.pool.New = func() interface{} {
+		return newDiskVScratch()
+	}
+	return cp
+}
+
+func (cp *cellCodecPool) get() *diskVScratch {
+	s := cp.pool.Get().(*diskVScratch)
+	s.buf.Reset()
+	return s
+}
+
+func (cp *cellCodecPool) put(s *diskVScratch) {
+	cp.pool.Put(s)
+}
+
+// encodeCellV2 serialises c with the FormatV2 codec (header byte + body +
+// trailing CRC32) and returns a standalone copy of the bytes that remains
+// valid after s is returned to its pool.
+func encodeCellV2(s *diskVScratch, c *Cell) ([]byte, error) {
+	if err := s.buf.WriteByte(FormatV2); err != nil {
+		return nil, err
+	}
+	if err := s.writeCellV2(c); err != nil {
+		return nil, err
+	}
+	sum := crc32.ChecksumIEEE(s.buf.Bytes())
+	if err := binary.Write(s.buf, binary.BigEndian, sum); err != nil {
+		return nil, err
+	}
+	out := make([]byte, s.buf.Len())
+	copy(out, s.buf.Bytes())
+	return out, nil
+}
+
+// decodeCellV2 parses a record produced by encodeCellV2, verifying its
+// trailing CRC32 before handing the body to readCellV2.
+func decodeCellV2(s *diskVScratch, b []byte) (*Cell, error) {
+	if len(b) < 1+crcLen || b[0] != FormatV2 {
+		return nil, errors.New("cellstore: not a v2 record")
+	}
+	body := b[1 : len(b)-crcLen]
+	want := binary.BigEndian.Uint32(b[len(b)-crcLen:])
+	if got := crc32.ChecksumIEEE(b[:len(b)-crcLen]); got != want {
+		return nil, errors.New("cellstore: checksum mismatch")
+	}
+	if _, err := s.buf.Write(body); err != nil {
+		return nil, err
+	}
+	s.reader = bytes.NewReader(s.buf.Bytes())
+	return s.readCellV2()
+}
@@ -0,0 +1,64 @@
+package xlsx
+
+import "io/ioutil"
+
+// CellStoreFactory constructs a new CellStore, typically one per sheet. File
+// uses it in place of the implicit NewDiskVCellStore wiring, so callers can
+// plug in BoltCellStore, BadgerCellStore, LRUCellStore, or any combination,
+// without File needing to know about any of them.
+type CellStoreFactory func() (CellStore, error)
+
+// DefaultCellStoreFactory is the factory File falls back to when none is
+// supplied via WithCellStoreFactory: a fresh DiskVCellStore per sheet,
+// matching the library's historical behaviour.
+func DefaultCellStoreFactory() (CellStore, error) {
+	return NewDiskVCellStore()
+}
+
+// WithCellStoreFactory overrides the CellStoreFactory a File uses to create
+// a CellStore for each sheet it manages.
+func WithCellStoreFactory(factory CellStoreFactory) FileOption {
+	return func(f *File) {
+		f.cellStoreFactory = factory
+	}
+}
+
+// NewBoltCellStoreFactory returns a CellStoreFactory that creates a fresh
+// BoltCellStore backed by its own temp file for each sheet.
+func NewBoltCellStoreFactory() CellStoreFactory {
+	return func() (CellStore, error) {
+		f, err := ioutil.TempFile("", "cellstore-bolt")
+		if err != nil {
+			return nil, err
+		}
+		path := f.Name()
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		return NewBoltCellStore(path)
+	}
+}
+
+// NewBadgerCellStoreFactory returns a CellStoreFactory that creates a fresh
+// BadgerCellStore backed by its own temp directory for each sheet.
+func NewBadgerCellStoreFactory() CellStoreFactory {
+	return func() (CellStore, error) {
+		dir, err := ioutil.TempDir("", "cellstore-badger")
+		if err != nil {
+			return nil, err
+		}
+		return NewBadgerCellStore(dir)
+	}
+}
+
+// NewLRUCellStoreFactory returns a CellStoreFactory that wraps the CellStore
+// produced by underlying in an LRUCellStore of the given capacity.
+func NewLRUCellStoreFactory(capacity int, underlying CellStoreFactory) CellStoreFactory {
+	return func() (CellStore, error) {
+		u, err := underlying()
+		if err != nil {
+			return nil, err
+		}
+		return NewLRUCellStore(capacity, u)
+	}
+}
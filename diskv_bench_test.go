@@ -0,0 +1,77 @@
+package xlsx
+
+import (
+	"strconv"
+	"testing"
+)
+
+func benchCell(row *Row, i int) *Cell {
+	cell := row.AddCell()
+	cell.Value = "value-" + strconv.Itoa(i)
+	cell.NumFmt = "General"
+	return cell
+}
+
+func BenchmarkDiskVCellStoreWriteCellV1(b *testing.B) {
+	benchmarkDiskVCellStoreWriteCell(b, FormatV1)
+}
+
+func BenchmarkDiskVCellStoreWriteCellV2(b *testing.B) {
+	benchmarkDiskVCellStoreWriteCell(b, FormatV2)
+}
+
+func benchmarkDiskVCellStoreWriteCell(b *testing.B, format byte) {
+	dCs, err := NewDiskVCellStore(WithFormat(format))
+	if err != nil {
+		b.Fatal(err)
+	}
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Bench")
+	row := sheet.AddRow()
+	cell := benchCell(row, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cs.WriteCell(cell); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDiskVCellStoreReadCellV1(b *testing.B) {
+	benchmarkDiskVCellStoreReadCell(b, FormatV1)
+}
+
+func BenchmarkDiskVCellStoreReadCellV2(b *testing.B) {
+	benchmarkDiskVCellStoreReadCell(b, FormatV2)
+}
+
+func benchmarkDiskVCellStoreReadCell(b *testing.B, format byte) {
+	dCs, err := NewDiskVCellStore(WithFormat(format))
+	if err != nil {
+		b.Fatal(err)
+	}
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Bench")
+	row := sheet.AddRow()
+	cell := benchCell(row, 0)
+	if err := cs.WriteCell(cell); err != nil {
+		b.Fatal(err)
+	}
+	key := cell.key()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cs.ReadCell(key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package xlsx
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// fillRow adds a fresh row to sheet and writes n cells to it through cs,
+// returning the row so the caller can exercise ForEachInRow against it.
+func fillRow(b *testing.B, cs *DiskVCellStore, sheet *Sheet, n int) *Row {
+	row := sheet.AddRow()
+	for i := 0; i < n; i++ {
+		cell := row.AddCell()
+		cell.Value = "value-" + strconv.Itoa(i)
+		cell.NumFmt = "General"
+		if err := cs.WriteCell(cell); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return row
+}
+
+// dirFileCount reports how many files a DiskVCellStore's base directory
+// contains, the proxy BenchmarkDiskVCellStoreDiskFootprint uses for the
+// one-file-per-cell layout's inode cost.
+func dirFileCount(b *testing.B, dir string) int {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return len(matches)
+}
+
+// BenchmarkDiskVCellStoreForEachInRowOneFilePerCell is the baseline: no
+// batching, so ForEachInRow does one diskv read per cell.
+func BenchmarkDiskVCellStoreForEachInRowOneFilePerCell(b *testing.B) {
+	dCs, err := NewDiskVCellStore()
+	if err != nil {
+		b.Fatal(err)
+	}
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+	file := NewFile()
+	sheet, _ := file.AddSheet("Bench")
+	row := fillRow(b, cs, sheet, 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cs.ForEachInRow(row, func(*Cell) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDiskVCellStoreForEachInRowBlockGzip(b *testing.B) {
+	benchmarkDiskVCellStoreForEachInRowBlock(b, CodecGzip)
+}
+
+func BenchmarkDiskVCellStoreForEachInRowBlockSnappy(b *testing.B) {
+	benchmarkDiskVCellStoreForEachInRowBlock(b, CodecSnappy)
+}
+
+// benchmarkDiskVCellStoreForEachInRowBlock batches the whole 1000-cell row
+// into one block (a blockSize bigger than the row never auto-flushes on
+// size, only on the Flush below), so ForEachInRow does a single block fetch
+// instead of 1000 individual reads.
+func benchmarkDiskVCellStoreForEachInRowBlock(b *testing.B, codec BlockCodec) {
+	dCs, err := NewDiskVCellStore(WithBlockSize(2000), WithCodec(codec))
+	if err != nil {
+		b.Fatal(err)
+	}
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+	file := NewFile()
+	sheet, _ := file.AddSheet("Bench")
+	row := fillRow(b, cs, sheet, 1000)
+	if err := cs.Flush(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cs.ForEachInRow(row, func(*Cell) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDiskVCellStoreDiskFootprint reports, via b.ReportMetric, the
+// number of files a 1000-cell, 10-row, 100-cells-per-block sheet leaves behind in each
+// layout: one-file-per-cell keeps one file per cell, batching keeps one file
+// per ~100 cells (10 rows of 100 cells each, one block per row).
+func BenchmarkDiskVCellStoreDiskFootprint(b *testing.B) {
+	b.Run("OneFilePerCell", func(b *testing.B) {
+		dCs, err := NewDiskVCellStore()
+		if err != nil {
+			b.Fatal(err)
+		}
+		cs := dCs.(*DiskVCellStore)
+		defer cs.Close()
+		file := NewFile()
+		sheet, _ := file.AddSheet("Bench")
+		for row := 0; row < 10; row++ {
+			fillRow(b, cs, sheet, 100)
+		}
+		b.ReportMetric(float64(dirFileCount(b, cs.baseDir)), "files")
+	})
+
+	b.Run("BatchedGzip", func(b *testing.B) {
+		dCs, err := NewDiskVCellStore(WithBlockSize(100), WithCodec(CodecGzip))
+		if err != nil {
+			b.Fatal(err)
+		}
+		cs := dCs.(*DiskVCellStore)
+		defer cs.Close()
+		file := NewFile()
+		sheet, _ := file.AddSheet("Bench")
+		for row := 0; row < 10; row++ {
+			fillRow(b, cs, sheet, 100)
+		}
+		b.ReportMetric(float64(dirFileCount(b, cs.baseDir)), "files")
+	})
+}
@@ -0,0 +1,135 @@
+package xlsx
+
+import (
+	"strconv"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDiskVCellStoreBlockRoundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore(WithBlockSize(4), WithCodec(CodecGzip))
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+	cells := make([]*Cell, 6)
+	for i := range cells {
+		cell := row.AddCell()
+		cell.Value = "value-" + strconv.Itoa(i)
+		cell.formula = "1+1"
+		cells[i] = cell
+		c.Assert(cs.WriteCell(cell), qt.IsNil)
+	}
+
+	// The first 4 cells filled a block and were flushed; the last 2 are
+	// still buffered in memory until two more cells of this row, a cell of
+	// another row, or a Flush arrive.
+	for _, cell := range cells {
+		got, err := cs.ReadCell(cell.key())
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Value, qt.Equals, cell.Value)
+		c.Assert(got.formula, qt.Equals, cell.formula)
+	}
+}
+
+func TestDiskVCellStoreBlockForEachInRow(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore(WithBlockSize(DefaultBlockSize), WithCodec(CodecSnappy))
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+	for i := 0; i < 10; i++ {
+		cell := row.AddCell()
+		cell.Value = "value-" + strconv.Itoa(i)
+		c.Assert(cs.WriteCell(cell), qt.IsNil)
+	}
+
+	var seen []string
+	err = cs.ForEachInRow(row, func(cell *Cell) error {
+		seen = append(seen, cell.Value)
+		return nil
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(seen, qt.HasLen, 10)
+}
+
+// TestDiskVCellStoreBlockForEachInRowMixedMode covers a row containing cells
+// written both before and after WithBlockSize takes effect: a legacy
+// per-cell write, then block mode switched on for the rest of the row.
+// ForEachInRow and ForEach must surface both, not just the blocked ones.
+func TestDiskVCellStoreBlockForEachInRowMixedMode(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore()
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+
+	legacy := row.AddCell()
+	legacy.Value = "legacy"
+	c.Assert(cs.WriteCell(legacy), qt.IsNil)
+
+	cs.blockSize = DefaultBlockSize
+	cs.codec = CodecGzip
+	for i := 0; i < 3; i++ {
+		cell := row.AddCell()
+		cell.Value = "blocked-" + strconv.Itoa(i)
+		c.Assert(cs.WriteCell(cell), qt.IsNil)
+	}
+	c.Assert(cs.Flush(), qt.IsNil)
+
+	var seen []string
+	c.Assert(cs.ForEachInRow(row, func(cell *Cell) error {
+		seen = append(seen, cell.Value)
+		return nil
+	}), qt.IsNil)
+	c.Assert(seen, qt.HasLen, 4)
+
+	seen = nil
+	c.Assert(cs.ForEach(func(cell *Cell) error {
+		seen = append(seen, cell.Value)
+		return nil
+	}), qt.IsNil)
+	c.Assert(seen, qt.HasLen, 4)
+}
+
+func TestDiskVCellStoreBlockDeleteCell(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore(WithBlockSize(2))
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+	a, b := row.AddCell(), row.AddCell()
+	a.Value, b.Value = "a", "b"
+	c.Assert(cs.WriteCell(a), qt.IsNil)
+	c.Assert(cs.WriteCell(b), qt.IsNil) // fills the block of size 2, flushing both
+
+	c.Assert(cs.DeleteCell(a.key()), qt.IsNil)
+
+	_, err = cs.ReadCell(a.key())
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	got, err := cs.ReadCell(b.key())
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Value, qt.Equals, "b")
+}
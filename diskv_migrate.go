@@ -0,0 +1,67 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// MigrateToV2 walks every record currently held by the store and rewrites any
+// entry still encoded in the legacy FormatV1 (or pre-header) layout to
+// FormatV2, leaving records that are already FormatV2 untouched. It is safe
+// to call on a store that's concurrently being read from or written to:
+// each record is migrated under its own shard lock, one at a time.
+func (cs *DiskVCellStore) MigrateToV2() error {
+	for key := range cs.store.Keys(nil) {
+		if err := cs.migrateKeyToV2(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *DiskVCellStore) migrateKeyToV2(key string) error {
+	lock := cs.shardFor(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b, err := cs.store.Read(key)
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 && b[0] == FormatV2 {
+		return nil
+	}
+
+	s := cs.getScratch()
+	defer cs.putScratch(s)
+
+	if len(b) > 0 && b[0] == FormatV1 {
+		if _, err := s.buf.Write(b[1:]); err != nil {
+			return err
+		}
+	} else {
+		if _, err := s.buf.Write(b); err != nil {
+			return err
+		}
+	}
+	s.reader = bytes.NewReader(s.buf.Bytes())
+	c, err := s.readCellV1()
+	if err != nil {
+		return err
+	}
+
+	s.buf.Reset()
+	if err := s.buf.WriteByte(FormatV2); err != nil {
+		return err
+	}
+	if err := s.writeCellV2(c); err != nil {
+		return err
+	}
+	sum := crc32.ChecksumIEEE(s.buf.Bytes())
+	if err := binary.Write(s.buf, binary.BigEndian, sum); err != nil {
+		return err
+	}
+
+	return cs.store.WriteStream(key, s.buf, true)
+}
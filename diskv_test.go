@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// testCellStoreConcurrency hammers WriteCell/ReadCell/ForEachInRow on cs from
+// many goroutines across overlapping rows to catch data races in the
+// implementation's internal synchronization. Shared by the DiskVCellStore and
+// MemoryCellStore tests below; run with -race.
+func testCellStoreConcurrency(t *testing.T, cs CellStore) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sheet, err := file.AddSheet("Test")
+	c.Assert(err, qt.IsNil)
+
+	const goroutines = 16
+	const rows = 8
+	const cols = 8
+
+	// Pre-build the rows/cells up front: the Cell/Row objects themselves
+	// aren't part of the concurrency contract under test here, only the
+	// CellStore that serialises/deserialises them.
+	cells := make([][]*Cell, rows)
+	for r := 0; r < rows; r++ {
+		row := sheet.AddRow()
+		cells[r] = make([]*Cell, cols)
+		for col := 0; col < cols; col++ {
+			cell := row.AddCell()
+			cell.Value = "r" + strconv.Itoa(r) + "c" + strconv.Itoa(col)
+			cells[r][col] = cell
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			row := cells[g%rows]
+			for i := 0; i < 50; i++ {
+				cell := row[i%cols]
+
+				err := cs.WriteCell(cell)
+				c.Check(err, qt.IsNil)
+
+				got, err := cs.ReadCell(cell.key())
+				c.Check(err, qt.IsNil)
+				if err == nil {
+					c.Check(got.Value, qt.Equals, cell.Value)
+				}
+
+				err = cs.ForEachInRow(sheet.Rows[g%rows], func(*Cell) error {
+					return nil
+				})
+				c.Check(err, qt.IsNil)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestDiskVCellStoreConcurrency exercises the shared scratch buffers and the
+// underlying diskv store across concurrent access. Run with -race.
+func TestDiskVCellStoreConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore()
+	c.Assert(err, qt.IsNil)
+	cs, ok := dCs.(*DiskVCellStore)
+	c.Assert(ok, qt.Equals, true)
+	defer cs.Close()
+
+	testCellStoreConcurrency(t, cs)
+}
+
+// TestMemoryCellStoreConcurrency is MemoryCellStore's share of the original
+// "make the CellStore interface and all implementations concurrency-safe"
+// request: the DiskVCellStore-only pass this request originally landed as
+// never touched MemoryCellStore or exercised it under -race.
+func TestMemoryCellStoreConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	mCs, err := NewMemoryCellStore()
+	c.Assert(err, qt.IsNil)
+	cs, ok := mCs.(*MemoryCellStore)
+	c.Assert(ok, qt.Equals, true)
+	defer cs.Close()
+
+	testCellStoreConcurrency(t, cs)
+}
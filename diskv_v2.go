@@ -0,0 +1,521 @@
+package xlsx
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// This file implements the FormatV2 on-disk record codec for DiskVCellStore:
+// length-prefixed (uvarint length + raw bytes) strings instead of
+// US-delimited scanning, explicit nullable flags, and fixed-width booleans
+// and varints with no separator bytes. The record as a whole is still
+// wrapped with a format header and trailing CRC32 by WriteCell/readCellLocked
+// in diskv.go; the methods here only deal with the body.
+
+// writeUvarintBytes writes len(b) as a uvarint followed by b itself.
+func (s *diskVScratch) writeUvarintBytes(b []byte) error {
+	n := binary.PutUvarint(s.ibuf, uint64(len(b)))
+	if _, err := s.buf.Write(s.ibuf[:n]); err != nil {
+		return err
+	}
+	_, err := s.buf.Write(b)
+	return err
+}
+
+// readUvarintBytes reads a uvarint length prefix followed by that many bytes.
+func (s *diskVScratch) readUvarintBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(s.reader)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(s.reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *diskVScratch) writeStringV2(str string) error {
+	return s.writeUvarintBytes([]byte(str))
+}
+
+func (s *diskVScratch) readStringV2() (string, error) {
+	b, err := s.readUvarintBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s *diskVScratch) writeBoolV2(b bool) error {
+	if b {
+		return s.buf.WriteByte(TRUE)
+	}
+	return s.buf.WriteByte(FALSE)
+}
+
+func (s *diskVScratch) readBoolV2() (bool, error) {
+	b, err := s.reader.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	return b == TRUE, nil
+}
+
+func (s *diskVScratch) writeIntV2(i int) error {
+	n := binary.PutVarint(s.ibuf, int64(i))
+	_, err := s.buf.Write(s.ibuf[:n])
+	return err
+}
+
+func (s *diskVScratch) readIntV2() (int, error) {
+	i, err := binary.ReadVarint(s.reader)
+	if err != nil {
+		return -1, err
+	}
+	return int(i), nil
+}
+
+func (s *diskVScratch) writeStringPointerV2(sp *string) error {
+	if err := s.writeBoolV2(sp == nil); err != nil {
+		return err
+	}
+	if sp == nil {
+		return nil
+	}
+	return s.writeStringV2(*sp)
+}
+
+func (s *diskVScratch) readStringPointerV2() (*string, error) {
+	isNil, err := s.readBoolV2()
+	if err != nil {
+		return nil, err
+	}
+	if isNil {
+		return nil, nil
+	}
+	str, err := s.readStringV2()
+	if err != nil {
+		return nil, err
+	}
+	return &str, nil
+}
+
+func (s *diskVScratch) writeBorderV2(b Border) error {
+	for _, str := range []string{b.Left, b.LeftColor, b.Right, b.RightColor, b.Top, b.TopColor, b.Bottom, b.BottomColor} {
+		if err := s.writeStringV2(str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *diskVScratch) readBorderV2() (Border, error) {
+	var err error
+	b := Border{}
+	if b.Left, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.LeftColor, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.Right, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.RightColor, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.Top, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.TopColor, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.Bottom, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	if b.BottomColor, err = s.readStringV2(); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+func (s *diskVScratch) writeFillV2(f Fill) error {
+	if err := s.writeStringV2(f.PatternType); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(f.BgColor); err != nil {
+		return err
+	}
+	return s.writeStringV2(f.FgColor)
+}
+
+func (s *diskVScratch) readFillV2() (Fill, error) {
+	var err error
+	f := Fill{}
+	if f.PatternType, err = s.readStringV2(); err != nil {
+		return f, err
+	}
+	if f.BgColor, err = s.readStringV2(); err != nil {
+		return f, err
+	}
+	if f.FgColor, err = s.readStringV2(); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func (s *diskVScratch) writeFontV2(f Font) error {
+	if err := s.writeIntV2(f.Size); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(f.Name); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(f.Family); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(f.Charset); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(f.Color); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(f.Bold); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(f.Italic); err != nil {
+		return err
+	}
+	return s.writeBoolV2(f.Underline)
+}
+
+func (s *diskVScratch) readFontV2() (Font, error) {
+	var err error
+	f := Font{}
+	if f.Size, err = s.readIntV2(); err != nil {
+		return f, err
+	}
+	if f.Name, err = s.readStringV2(); err != nil {
+		return f, err
+	}
+	if f.Family, err = s.readIntV2(); err != nil {
+		return f, err
+	}
+	if f.Charset, err = s.readIntV2(); err != nil {
+		return f, err
+	}
+	if f.Color, err = s.readStringV2(); err != nil {
+		return f, err
+	}
+	if f.Bold, err = s.readBoolV2(); err != nil {
+		return f, err
+	}
+	if f.Italic, err = s.readBoolV2(); err != nil {
+		return f, err
+	}
+	if f.Underline, err = s.readBoolV2(); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func (s *diskVScratch) writeAlignmentV2(a Alignment) error {
+	if err := s.writeStringV2(a.Horizontal); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(a.Indent); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(a.ShrinkToFit); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(a.TextRotation); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(a.Vertical); err != nil {
+		return err
+	}
+	return s.writeBoolV2(a.WrapText)
+}
+
+func (s *diskVScratch) readAlignmentV2() (Alignment, error) {
+	var err error
+	a := Alignment{}
+	if a.Horizontal, err = s.readStringV2(); err != nil {
+		return a, err
+	}
+	if a.Indent, err = s.readIntV2(); err != nil {
+		return a, err
+	}
+	if a.ShrinkToFit, err = s.readBoolV2(); err != nil {
+		return a, err
+	}
+	if a.TextRotation, err = s.readIntV2(); err != nil {
+		return a, err
+	}
+	if a.Vertical, err = s.readStringV2(); err != nil {
+		return a, err
+	}
+	if a.WrapText, err = s.readBoolV2(); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func (s *diskVScratch) writeStyleV2(st *Style) error {
+	if err := s.writeBorderV2(st.Border); err != nil {
+		return err
+	}
+	if err := s.writeFillV2(st.Fill); err != nil {
+		return err
+	}
+	if err := s.writeFontV2(st.Font); err != nil {
+		return err
+	}
+	if err := s.writeAlignmentV2(st.Alignment); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(st.ApplyBorder); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(st.ApplyFill); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(st.ApplyFont); err != nil {
+		return err
+	}
+	return s.writeBoolV2(st.ApplyAlignment)
+}
+
+func (s *diskVScratch) readStyleV2() (*Style, error) {
+	var err error
+	st := &Style{}
+	if st.Border, err = s.readBorderV2(); err != nil {
+		return st, err
+	}
+	if st.Fill, err = s.readFillV2(); err != nil {
+		return st, err
+	}
+	if st.Font, err = s.readFontV2(); err != nil {
+		return st, err
+	}
+	if st.Alignment, err = s.readAlignmentV2(); err != nil {
+		return st, err
+	}
+	if st.ApplyBorder, err = s.readBoolV2(); err != nil {
+		return st, err
+	}
+	if st.ApplyFill, err = s.readBoolV2(); err != nil {
+		return st, err
+	}
+	if st.ApplyFont, err = s.readBoolV2(); err != nil {
+		return st, err
+	}
+	if st.ApplyAlignment, err = s.readBoolV2(); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+func (s *diskVScratch) writeDataValidationV2(dv *xlsxDataValidation) error {
+	if err := s.writeBoolV2(dv.AllowBlank); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(dv.ShowInputMessage); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(dv.ShowErrorMessage); err != nil {
+		return err
+	}
+	if err := s.writeStringPointerV2(dv.ErrorStyle); err != nil {
+		return err
+	}
+	if err := s.writeStringPointerV2(dv.ErrorTitle); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(dv.Operator); err != nil {
+		return err
+	}
+	if err := s.writeStringPointerV2(dv.Error); err != nil {
+		return err
+	}
+	if err := s.writeStringPointerV2(dv.PromptTitle); err != nil {
+		return err
+	}
+	if err := s.writeStringPointerV2(dv.Prompt); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(dv.Type); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(dv.Sqref); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(dv.Formula1); err != nil {
+		return err
+	}
+	return s.writeStringV2(dv.Formula2)
+}
+
+func (s *diskVScratch) readDataValidationV2() (*xlsxDataValidation, error) {
+	var err error
+	dv := &xlsxDataValidation{}
+	if dv.AllowBlank, err = s.readBoolV2(); err != nil {
+		return dv, err
+	}
+	if dv.ShowInputMessage, err = s.readBoolV2(); err != nil {
+		return dv, err
+	}
+	if dv.ShowErrorMessage, err = s.readBoolV2(); err != nil {
+		return dv, err
+	}
+	if dv.ErrorStyle, err = s.readStringPointerV2(); err != nil {
+		return dv, err
+	}
+	if dv.ErrorTitle, err = s.readStringPointerV2(); err != nil {
+		return dv, err
+	}
+	if dv.Operator, err = s.readStringV2(); err != nil {
+		return dv, err
+	}
+	if dv.Error, err = s.readStringPointerV2(); err != nil {
+		return dv, err
+	}
+	if dv.PromptTitle, err = s.readStringPointerV2(); err != nil {
+		return dv, err
+	}
+	if dv.Prompt, err = s.readStringPointerV2(); err != nil {
+		return dv, err
+	}
+	if dv.Type, err = s.readStringV2(); err != nil {
+		return dv, err
+	}
+	if dv.Sqref, err = s.readStringV2(); err != nil {
+		return dv, err
+	}
+	if dv.Formula1, err = s.readStringV2(); err != nil {
+		return dv, err
+	}
+	if dv.Formula2, err = s.readStringV2(); err != nil {
+		return dv, err
+	}
+	return dv, nil
+}
+
+func (s *diskVScratch) writeCellV2(c *Cell) error {
+	if err := s.writeStringV2(c.Value); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(c.formula); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(c.style != nil); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(c.NumFmt); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(c.date1904); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(c.Hidden); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(c.HMerge); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(c.VMerge); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(int(c.cellType)); err != nil {
+		return err
+	}
+	if err := s.writeBoolV2(c.DataValidation != nil); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(c.Hyperlink.DisplayString); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(c.Hyperlink.Link); err != nil {
+		return err
+	}
+	if err := s.writeStringV2(c.Hyperlink.Tooltip); err != nil {
+		return err
+	}
+	if err := s.writeIntV2(c.num); err != nil {
+		return err
+	}
+	if c.style != nil {
+		if err := s.writeStyleV2(c.style); err != nil {
+			return err
+		}
+	}
+	if c.DataValidation != nil {
+		if err := s.writeDataValidationV2(c.DataValidation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *diskVScratch) readCellV2() (*Cell, error) {
+	var err error
+	var cellType int
+	var hasStyle, hasDataValidation bool
+	c := &Cell{}
+	if c.Value, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if c.formula, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if hasStyle, err = s.readBoolV2(); err != nil {
+		return c, err
+	}
+	if c.NumFmt, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if c.date1904, err = s.readBoolV2(); err != nil {
+		return c, err
+	}
+	if c.Hidden, err = s.readBoolV2(); err != nil {
+		return c, err
+	}
+	if c.HMerge, err = s.readIntV2(); err != nil {
+		return c, err
+	}
+	if c.VMerge, err = s.readIntV2(); err != nil {
+		return c, err
+	}
+	if cellType, err = s.readIntV2(); err != nil {
+		return c, err
+	}
+	c.cellType = CellType(cellType)
+	if hasDataValidation, err = s.readBoolV2(); err != nil {
+		return c, err
+	}
+	if c.Hyperlink.DisplayString, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if c.Hyperlink.Link, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if c.Hyperlink.Tooltip, err = s.readStringV2(); err != nil {
+		return c, err
+	}
+	if c.num, err = s.readIntV2(); err != nil {
+		return c, err
+	}
+	if hasStyle {
+		if c.style, err = s.readStyleV2(); err != nil {
+			return c, err
+		}
+	}
+	if hasDataValidation {
+		if c.DataValidation, err = s.readDataValidationV2(); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,59 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDiskVCellStoreV2RoundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore(WithFormat(FormatV2))
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	cell.Value = "value"
+	cell.formula = "1+1"
+	cell.NumFmt = "General"
+
+	c.Assert(cs.WriteCell(cell), qt.IsNil)
+
+	got, err := cs.ReadCell(cell.key())
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Value, qt.Equals, cell.Value)
+	c.Assert(got.formula, qt.Equals, cell.formula)
+	c.Assert(got.NumFmt, qt.Equals, cell.NumFmt)
+}
+
+func TestDiskVCellStoreMigrateToV2(t *testing.T) {
+	c := qt.New(t)
+
+	dCs, err := NewDiskVCellStore(WithFormat(FormatV1))
+	c.Assert(err, qt.IsNil)
+	cs := dCs.(*DiskVCellStore)
+	defer cs.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	cell.Value = "legacy value"
+
+	c.Assert(cs.WriteCell(cell), qt.IsNil)
+
+	c.Assert(cs.MigrateToV2(), qt.IsNil)
+
+	raw, err := cs.store.Read(cell.key())
+	c.Assert(err, qt.IsNil)
+	c.Assert(raw[0], qt.Equals, FormatV2)
+
+	got, err := cs.ReadCell(cell.key())
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Value, qt.Equals, cell.Value)
+}
@@ -0,0 +1,88 @@
+package xlsx
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// File is an in-memory representation of a single xlsx workbook: a set of
+// named Sheets plus the shared strings table they reference. Each Sheet is
+// given its own CellStore obtained from cellStoreFactory (see FileOption,
+// WithCellStoreFactory), so the backing storage can be swapped
+// (DiskVCellStore, BoltCellStore, BadgerCellStore, an LRU tier over any of
+// them, ...) without File itself needing to know which. Cells added through
+// Sheet.AddRow/Row.AddCell are not written to that CellStore.
+type File struct {
+	Sheet    map[string]*Sheet
+	Sheets   []*Sheet
+	Date1904 bool
+
+	cellStoreFactory CellStoreFactory
+
+	mu                sync.Mutex
+	sharedStrings     []string
+	sharedStringIndex map[string]int
+
+	// streamedSheetData holds, per sheet, the temp file a StreamWriter has
+	// Flush-ed its XML into (see spliceStreamedSheetXML in stream_writer.go).
+	// Save splices this in verbatim for that sheet instead of marshaling its
+	// Rows/Cells, and removes the entry once it has done so.
+	streamedSheetData map[*Sheet]*os.File
+}
+
+// FileOption configures a File at construction time via NewFile.
+type FileOption func(*File)
+
+// WithCellStoreFactory is defined in cellstore_factory.go alongside the
+// other CellStoreFactory constructors.
+
+// NewFile returns an empty File ready to have sheets added to it. Without
+// WithCellStoreFactory, each AddSheet call gets its own fresh DiskVCellStore
+// via DefaultCellStoreFactory, matching the library's historical behaviour.
+func NewFile(opts ...FileOption) *File {
+	f := &File{
+		Sheet:             make(map[string]*Sheet),
+		cellStoreFactory:  DefaultCellStoreFactory,
+		sharedStringIndex: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// AddSheet creates a new, empty Sheet named sheetName, backed by a CellStore
+// from f's CellStoreFactory, and returns it. It returns an error if a sheet
+// with that name already exists or if the factory fails to construct a
+// CellStore.
+func (f *File) AddSheet(sheetName string) (*Sheet, error) {
+	if _, exists := f.Sheet[sheetName]; exists {
+		return nil, fmt.Errorf("xlsx: sheet %q already exists", sheetName)
+	}
+
+	cs, err := f.cellStoreFactory()
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &Sheet{Name: sheetName, File: f, cellStore: cs}
+	f.Sheet[sheetName] = sheet
+	f.Sheets = append(f.Sheets, sheet)
+	return sheet, nil
+}
+
+// addSharedString returns the index of s in f's shared strings table,
+// adding it if this is the first time s has been seen.
+func (f *File) addSharedString(s string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if idx, ok := f.sharedStringIndex[s]; ok {
+		return idx
+	}
+	idx := len(f.sharedStrings)
+	f.sharedStrings = append(f.sharedStrings, s)
+	f.sharedStringIndex[s] = idx
+	return idx
+}
@@ -0,0 +1,181 @@
+package xlsx
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// lruEntry is the value held by each element of LRUCellStore's list; key is
+// kept alongside the cell so an evicted element can be removed from the
+// index map without recomputing c.key().
+type lruEntry struct {
+	key  string
+	cell *Cell
+}
+
+// LRUCellStore is a tiered CellStore: the capacity most-recently-used cells
+// are kept in memory, and anything evicted spills to an underlying
+// CellStore. Sheets that are mostly written and read row-by-row (the common
+// pattern) keep their hot rows entirely in memory and never pay disk I/O for
+// them, while a sheet far larger than capacity still completes, at the cost
+// of falling back to the underlying store for cold cells.
+type LRUCellStore struct {
+	mu         sync.Mutex
+	capacity   int
+	ll         *list.List
+	items      map[string]*list.Element
+	underlying CellStore
+}
+
+// NewLRUCellStore returns a CellStore that keeps at most capacity cells in
+// memory, spilling the least-recently-used ones to underlying as capacity is
+// exceeded.
+func NewLRUCellStore(capacity int, underlying CellStore) (CellStore, error) {
+	return &LRUCellStore{
+		capacity:   capacity,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		underlying: underlying,
+	}, nil
+}
+
+func (cs *LRUCellStore) WriteCell(c *Cell) error {
+	key := c.key()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if el, ok := cs.items[key]; ok {
+		cs.ll.MoveToFront(el)
+		el.Value.(*lruEntry).cell = c
+		return nil
+	}
+
+	el := cs.ll.PushFront(&lruEntry{key: key, cell: c})
+	cs.items[key] = el
+	return cs.evictLocked()
+}
+
+// evictLocked spills the least-recently-used entries to the underlying store
+// until the in-memory tier is back down to capacity. The caller must hold
+// cs.mu.
+func (cs *LRUCellStore) evictLocked() error {
+	for cs.ll.Len() > cs.capacity {
+		el := cs.ll.Back()
+		if el == nil {
+			break
+		}
+		entry := el.Value.(*lruEntry)
+		if err := cs.underlying.WriteCell(entry.cell); err != nil {
+			return err
+		}
+		cs.ll.Remove(el)
+		delete(cs.items, entry.key)
+	}
+	return nil
+}
+
+func (cs *LRUCellStore) ReadCell(key string) (*Cell, error) {
+	cs.mu.Lock()
+	if el, ok := cs.items[key]; ok {
+		cs.ll.MoveToFront(el)
+		c := el.Value.(*lruEntry).cell
+		cs.mu.Unlock()
+		return c, nil
+	}
+	cs.mu.Unlock()
+	return cs.underlying.ReadCell(key)
+}
+
+func (cs *LRUCellStore) DeleteCell(key string) error {
+	cs.mu.Lock()
+	el, hot := cs.items[key]
+	if hot {
+		cs.ll.Remove(el)
+		delete(cs.items, key)
+	}
+	cs.mu.Unlock()
+
+	if hot {
+		// A cell that's still in the hot tier was either never spilled, in
+		// which case underlying has nothing to erase, or was spilled and then
+		// overwritten, in which case underlying still holds a stale copy that
+		// would resurface once the hot entry is gone. Erase only in the latter
+		// case, so the never-spilled case doesn't surface a spurious not-found
+		// error from underlying.DeleteCell.
+		if _, err := cs.underlying.ReadCell(key); err != nil {
+			if _, notFound := err.(*CellNotFoundError); notFound {
+				return nil
+			}
+			return err
+		}
+	}
+	return cs.underlying.DeleteCell(key)
+}
+
+func (cs *LRUCellStore) ForEach(cvf CellVisitorFunc) error {
+	cs.mu.Lock()
+	seen := make(map[string]bool, len(cs.items))
+	hot := make([]*Cell, 0, len(cs.items))
+	for key, el := range cs.items {
+		seen[key] = true
+		hot = append(hot, el.Value.(*lruEntry).cell)
+	}
+	cs.mu.Unlock()
+
+	for _, c := range hot {
+		if err := cvf(c); err != nil {
+			return err
+		}
+	}
+	return cs.underlying.ForEach(func(c *Cell) error {
+		if seen[c.key()] {
+			return nil
+		}
+		return cvf(c)
+	})
+}
+
+func (cs *LRUCellStore) ForEachInRow(r *Row, cvf CellVisitorFunc) error {
+	pref := r.makeCellKeyRowPrefix()
+
+	cs.mu.Lock()
+	seen := make(map[string]bool)
+	var hot []*Cell
+	for key, el := range cs.items {
+		if strings.HasPrefix(key, pref) {
+			seen[key] = true
+			hot = append(hot, el.Value.(*lruEntry).cell)
+		}
+	}
+	cs.mu.Unlock()
+
+	for _, c := range hot {
+		if err := cvf(c); err != nil {
+			return err
+		}
+	}
+	return cs.underlying.ForEachInRow(r, func(c *Cell) error {
+		if seen[c.key()] {
+			return nil
+		}
+		return cvf(c)
+	})
+}
+
+func (cs *LRUCellStore) Close() error {
+	cs.mu.Lock()
+	for cs.ll.Len() > 0 {
+		el := cs.ll.Back()
+		entry := el.Value.(*lruEntry)
+		if err := cs.underlying.WriteCell(entry.cell); err != nil {
+			cs.mu.Unlock()
+			return err
+		}
+		cs.ll.Remove(el)
+		delete(cs.items, entry.key)
+	}
+	cs.mu.Unlock()
+	return cs.underlying.Close()
+}
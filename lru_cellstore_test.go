@@ -0,0 +1,38 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLRUCellStoreSpillover(t *testing.T) {
+	c := qt.New(t)
+
+	underlying, err := NewDiskVCellStore()
+	c.Assert(err, qt.IsNil)
+
+	lru, err := NewLRUCellStore(2, underlying)
+	c.Assert(err, qt.IsNil)
+	defer lru.Close()
+
+	file := NewFile()
+	sheet, _ := file.AddSheet("Test")
+	row := sheet.AddRow()
+
+	cells := make([]*Cell, 4)
+	for i := range cells {
+		cell := row.AddCell()
+		cell.Value = "value"
+		cells[i] = cell
+		c.Assert(lru.WriteCell(cell), qt.IsNil)
+	}
+
+	// With a capacity of 2, writing 4 cells should have spilled the first
+	// two to the underlying store while keeping the last two in memory.
+	for _, cell := range cells {
+		got, err := lru.ReadCell(cell.key())
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Value, qt.Equals, "value")
+	}
+}
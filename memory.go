@@ -0,0 +1,82 @@
+package xlsx
+
+import "sync"
+
+// MemoryCellStore is the simplest CellStore: cells live only in a map, never
+// touching disk. It exists for small sheets and tests where DiskVCellStore's
+// durability isn't worth its I/O, and is safe for concurrent use by multiple
+// goroutines.
+type MemoryCellStore struct {
+	mu    sync.RWMutex
+	cells map[string]*Cell
+}
+
+// NewMemoryCellStore returns an empty MemoryCellStore.
+func NewMemoryCellStore() (CellStore, error) {
+	return &MemoryCellStore{cells: make(map[string]*Cell)}, nil
+}
+
+func (cs *MemoryCellStore) WriteCell(c *Cell) error {
+	key := c.key()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.cells[key] = c
+	return nil
+}
+
+func (cs *MemoryCellStore) ReadCell(key string) (*Cell, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	c, ok := cs.cells[key]
+	if !ok {
+		return nil, NewCellNotFoundError(key, "not found in memory cellstore")
+	}
+	return c, nil
+}
+
+func (cs *MemoryCellStore) DeleteCell(key string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.cells, key)
+	return nil
+}
+
+func (cs *MemoryCellStore) ForEach(cvf CellVisitorFunc) error {
+	cs.mu.RLock()
+	cells := make([]*Cell, 0, len(cs.cells))
+	for _, c := range cs.cells {
+		cells = append(cells, c)
+	}
+	cs.mu.RUnlock()
+
+	for _, c := range cells {
+		if err := cvf(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *MemoryCellStore) ForEachInRow(r *Row, cvf CellVisitorFunc) error {
+	pref := r.makeCellKeyRowPrefix()
+
+	cs.mu.RLock()
+	var cells []*Cell
+	for key, c := range cs.cells {
+		if len(key) >= len(pref) && key[:len(pref)] == pref {
+			cells = append(cells, c)
+		}
+	}
+	cs.mu.RUnlock()
+
+	for _, c := range cells {
+		if err := cvf(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *MemoryCellStore) Close() error {
+	return nil
+}
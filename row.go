@@ -0,0 +1,24 @@
+package xlsx
+
+import "strconv"
+
+// Row is one row of a Sheet, holding its cells in column order.
+type Row struct {
+	Sheet *Sheet
+	Cells []*Cell
+	num   int // 0-based row index within Sheet
+}
+
+// AddCell appends a new, empty Cell to the row and returns it.
+func (r *Row) AddCell() *Cell {
+	cell := &Cell{Row: r, num: len(r.Cells)}
+	r.Cells = append(r.Cells, cell)
+	return cell
+}
+
+// makeCellKeyRowPrefix returns the CellStore key prefix shared by every cell
+// in this row, used to scope ForEachInRow and shard-lock lookups to a
+// single row without needing every column index up front.
+func (r *Row) makeCellKeyRowPrefix() string {
+	return strconv.Itoa(r.num) + ":"
+}
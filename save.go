@@ -0,0 +1,116 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	xmlHeader      = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`
+	sheetNamespace = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+)
+
+// Save writes f out as a zip archive at path, creating or truncating it.
+func (f *File) Save(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Write serialises f as a zip archive to w, one xl/worksheets/sheetN.xml
+// entry per sheet in the order sheets were added (File.Sheets). A sheet
+// that was populated through a StreamWriter has its Flush-ed temp file
+// spliced in verbatim instead of being re-marshaled from Rows/Cells, which
+// is the only way its content reaches the saved workbook at all.
+func (f *File) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for i, sheet := range f.Sheets {
+		entry, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := f.writeSheetXML(entry, sheet); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (f *File) writeSheetXML(w io.Writer, sheet *Sheet) error {
+	if _, err := io.WriteString(w, xmlHeader); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<worksheet xmlns="`+sheetNamespace+`">`); err != nil {
+		return err
+	}
+
+	if tmp, streamed := f.streamedSheetData[sheet]; streamed {
+		if err := f.spliceStreamedSheetData(w, sheet, tmp); err != nil {
+			return err
+		}
+	} else if err := writeSheetDataXML(w, sheet); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, `</worksheet>`)
+	return err
+}
+
+// spliceStreamedSheetData copies tmp's already-complete <sheetData>...
+// (and any trailing <mergeCells>) straight into w, then closes, removes and
+// forgets tmp, so a second Save doesn't try to splice an already-consumed
+// file back in.
+func (f *File) spliceStreamedSheetData(w io.Writer, sheet *Sheet, tmp *os.File) error {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, tmp); err != nil {
+		return err
+	}
+	name := tmp.Name()
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	delete(f.streamedSheetData, sheet)
+	return os.Remove(name)
+}
+
+// writeSheetDataXML marshals sheet's in-memory Rows/Cells into a
+// <sheetData> element, the non-streamed counterpart to a StreamWriter's
+// output.
+func writeSheetDataXML(w io.Writer, sheet *Sheet) error {
+	if _, err := io.WriteString(w, "<sheetData>"); err != nil {
+		return err
+	}
+	for rowIdx, row := range sheet.Rows {
+		if row == nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, `<row r="%d">`, rowIdx+1); err != nil {
+			return err
+		}
+		for colIdx, cell := range row.Cells {
+			if cell == nil {
+				continue
+			}
+			ref := ColIndexToLetters(colIdx) + strconv.Itoa(rowIdx+1)
+			if _, err := fmt.Fprintf(w, `<c r="%s" t="str"><v>%s</v></c>`, ref, escapeXMLText(cell.Value)); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "</row>"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</sheetData>")
+	return err
+}
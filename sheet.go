@@ -0,0 +1,33 @@
+package xlsx
+
+// Sheet is a single worksheet within a File. Its cells are held in Rows for
+// in-memory access (AddRow/AddCell, Cell, Recalculate). It also owns
+// cellStore, a CellStore from the owning File's CellStoreFactory; AddRow and
+// AddCell do not write cells to it.
+type Sheet struct {
+	Name string
+	File *File
+	Rows []*Row
+
+	cellStore CellStore
+}
+
+// AddRow appends a new, empty Row to the sheet and returns it.
+func (s *Sheet) AddRow() *Row {
+	row := &Row{Sheet: s, num: len(s.Rows)}
+	s.Rows = append(s.Rows, row)
+	return row
+}
+
+// Cell returns the cell at the given 0-based row and column, or nil if
+// either index is out of range or the row has no cell that far.
+func (s *Sheet) Cell(row, col int) *Cell {
+	if row < 0 || row >= len(s.Rows) || s.Rows[row] == nil {
+		return nil
+	}
+	r := s.Rows[row]
+	if col < 0 || col >= len(r.Cells) {
+		return nil
+	}
+	return r.Cells[col]
+}
@@ -0,0 +1,252 @@
+package xlsx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RowOption configures how StreamWriter.SetRow serialises a single row.
+type RowOption func(*streamRowOpts)
+
+type streamRowOpts struct {
+	height *float64
+	hidden bool
+}
+
+// RowHeight sets an explicit row height (in points) for the row.
+func RowHeight(h float64) RowOption {
+	return func(o *streamRowOpts) { o.height = &h }
+}
+
+// RowHidden marks the row as hidden.
+func RowHidden(hidden bool) RowOption {
+	return func(o *streamRowOpts) { o.hidden = hidden }
+}
+
+// StyledValue pairs a raw cell value with a pre-resolved style ID, so
+// StreamWriter.SetRow can reference a style by its index into the styles
+// table instead of taking a *Style (which would require the usual
+// add-style-get-index bookkeeping StreamWriter is specifically trying to
+// avoid on the hot path).
+type StyledValue struct {
+	Value   interface{}
+	StyleID int
+}
+
+// StreamWriter appends serialised <row>/<c> XML fragments for one sheet
+// directly to a temp file, without ever constructing Row/Cell objects or
+// touching the sheet's CellStore. It exists for generating very large
+// sheets: the only state it keeps in memory is a small buffered writer and
+// any pending MergeCell/SetColWidth directives.
+//
+// Exactly one StreamWriter should be active for a given sheet at a time.
+// Call SetRow for each row in increasing row order, then Flush exactly once.
+type StreamWriter struct {
+	file    *File
+	sheet   *Sheet
+	tmpFile *os.File
+	w       *bufio.Writer
+
+	mergeCells []string
+	colWidths  map[int]float64
+
+	// InlineStrings selects whether string cell values are written inline
+	// (t="inlineStr") instead of being added to the shared strings table and
+	// referenced by index (t="s", the default).
+	// Inline strings skip the shared-strings bookkeeping entirely, which
+	// matters when streaming millions of distinct values; shared strings
+	// are smaller on disk when values repeat often.
+	InlineStrings bool
+
+	flushed bool
+}
+
+// NewStreamWriter returns a StreamWriter for a new sheet named sheetName.
+func (f *File) NewStreamWriter(sheetName string) (*StreamWriter, error) {
+	sheet, err := f.AddSheet(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile("", "streamwriter")
+	if err != nil {
+		return nil, err
+	}
+
+	sw := &StreamWriter{
+		file:      f,
+		sheet:     sheet,
+		tmpFile:   tmp,
+		w:         bufio.NewWriter(tmp),
+		colWidths: make(map[int]float64),
+	}
+	if _, err := sw.w.WriteString("<sheetData>"); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// SetRow writes one row of values starting at axis (e.g. "A1"), serialising
+// each value straight to the stream. Supported value types are nil, bool,
+// int, float64, string and StyledValue (to attach a style ID to any of the
+// above).
+func (sw *StreamWriter) SetRow(axis string, values []interface{}, opts ...RowOption) error {
+	if sw.flushed {
+		return errors.New("xlsx: StreamWriter already flushed")
+	}
+
+	col, row, err := GetCoordsFromCellIDString(axis)
+	if err != nil {
+		return err
+	}
+
+	ro := streamRowOpts{}
+	for _, opt := range opts {
+		opt(&ro)
+	}
+
+	rowNum := row + 1 // GetCoordsFromCellIDString is 0-based; row XML attrs are 1-based
+
+	fmt.Fprintf(sw.w, `<row r="%d"`, rowNum)
+	if ro.height != nil {
+		fmt.Fprintf(sw.w, ` ht="%s" customHeight="1"`, strconv.FormatFloat(*ro.height, 'f', -1, 64))
+	}
+	if ro.hidden {
+		if _, err := sw.w.WriteString(` hidden="1"`); err != nil {
+			return err
+		}
+	}
+	if _, err := sw.w.WriteString(">"); err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		ref := ColIndexToLetters(col+i) + strconv.Itoa(rowNum)
+		if err := sw.writeCellXML(ref, v); err != nil {
+			return err
+		}
+	}
+
+	if _, err := sw.w.WriteString("</row>"); err != nil {
+		return err
+	}
+	return sw.w.Flush()
+}
+
+func (sw *StreamWriter) writeCellXML(ref string, v interface{}) error {
+	styleID := 0
+	value := v
+	if sv, ok := v.(StyledValue); ok {
+		styleID = sv.StyleID
+		value = sv.Value
+	}
+
+	switch val := value.(type) {
+	case nil:
+		_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d"/>`, ref, styleID)
+		return err
+	case string:
+		if sw.InlineStrings {
+			_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, styleID, escapeXMLText(val))
+			return err
+		}
+		idx := sw.file.addSharedString(val)
+		_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d" t="s"><v>%d</v></c>`, ref, styleID, idx)
+		return err
+	case bool:
+		b := 0
+		if val {
+			b = 1
+		}
+		_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d" t="b"><v>%d</v></c>`, ref, styleID, b)
+		return err
+	case int:
+		_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d"><v>%d</v></c>`, ref, styleID, val)
+		return err
+	case float64:
+		_, err := fmt.Fprintf(sw.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleID, strconv.FormatFloat(val, 'f', -1, 64))
+		return err
+	default:
+		return fmt.Errorf("xlsx: unsupported StreamWriter cell value type %T", value)
+	}
+}
+
+// MergeCell queues a merge of the rectangular range from topLeft to
+// bottomRight (e.g. "A1", "C1"), written out when Flush runs.
+func (sw *StreamWriter) MergeCell(topLeft, bottomRight string) {
+	sw.mergeCells = append(sw.mergeCells, fmt.Sprintf(`<mergeCell ref="%s:%s"/>`, topLeft, bottomRight))
+}
+
+// SetColWidth records a width, in characters, for the zero-indexed column
+// col. Flush does not write recorded widths into the sheet XML, so they do
+// not reach the saved file.
+func (sw *StreamWriter) SetColWidth(col int, width float64) {
+	sw.colWidths[col] = width
+}
+
+// Flush finalises the streamed sheetData, appends any queued merge-cell
+// directives, and splices the temp file into place as the sheet's XML body
+// so it's picked up the next time the File is saved. Flush must be called
+// exactly once; calling it again is a no-op.
+func (sw *StreamWriter) Flush() error {
+	if sw.flushed {
+		return nil
+	}
+
+	if _, err := sw.w.WriteString("</sheetData>"); err != nil {
+		return err
+	}
+	if len(sw.mergeCells) > 0 {
+		if _, err := fmt.Fprintf(sw.w, `<mergeCells count="%d">`, len(sw.mergeCells)); err != nil {
+			return err
+		}
+		for _, m := range sw.mergeCells {
+			if _, err := sw.w.WriteString(m); err != nil {
+				return err
+			}
+		}
+		if _, err := sw.w.WriteString("</mergeCells>"); err != nil {
+			return err
+		}
+	}
+	if err := sw.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := sw.tmpFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	sw.flushed = true
+	return sw.file.spliceStreamedSheetXML(sw.sheet, sw.tmpFile)
+}
+
+// escapeXMLText escapes the five characters that aren't otherwise legal
+// inside XML character data.
+func escapeXMLText(s string) string {
+	r := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"'", "&apos;",
+	)
+	return r.Replace(s)
+}
+
+// spliceStreamedSheetXML registers tmp (already rewound to its start) as the
+// XML body for sheet: saving the File writes tmp's contents directly into
+// sheetN.xml instead of marshaling sheet's Rows/Cells. The temp file is
+// removed once its contents have been copied out during Save.
+func (f *File) spliceStreamedSheetXML(sheet *Sheet, tmp *os.File) error {
+	if f.streamedSheetData == nil {
+		f.streamedSheetData = make(map[*Sheet]*os.File)
+	}
+	f.streamedSheetData[sheet] = tmp
+	return nil
+}
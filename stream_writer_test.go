@@ -0,0 +1,91 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestStreamWriterMergeAndColWidth(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sw, err := file.NewStreamWriter("Sheet1")
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(sw.SetRow("A1", []interface{}{"merged", nil}), qt.IsNil)
+	sw.MergeCell("A1", "B1")
+	sw.SetColWidth(0, 20)
+
+	c.Assert(sw.Flush(), qt.IsNil)
+	c.Assert(sw.Flush(), qt.IsNil) // idempotent
+}
+
+// TestStreamWriterFlushSurvivesSave confirms Flush's splice actually reaches
+// the saved workbook: without it, Save would have nothing to marshal for a
+// streamed sheet (StreamWriter never touches Rows/Cells/CellStore) and the
+// sheet's xl/worksheets entry would come back empty.
+func TestStreamWriterFlushSurvivesSave(t *testing.T) {
+	c := qt.New(t)
+
+	file := NewFile()
+	sw, err := file.NewStreamWriter("Sheet1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(sw.SetRow("A1", []interface{}{"streamed-value"}), qt.IsNil)
+	c.Assert(sw.Flush(), qt.IsNil)
+
+	tmp, err := ioutil.TempFile("", "streamwriter-save")
+	c.Assert(err, qt.IsNil)
+	path := tmp.Name()
+	c.Assert(tmp.Close(), qt.IsNil)
+
+	c.Assert(file.Save(path), qt.IsNil)
+
+	zr, err := zip.OpenReader(path)
+	c.Assert(err, qt.IsNil)
+	defer zr.Close()
+
+	var sheetXML string
+	for _, zf := range zr.File {
+		if zf.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := zf.Open()
+		c.Assert(err, qt.IsNil)
+		b, err := ioutil.ReadAll(rc)
+		c.Assert(rc.Close(), qt.IsNil)
+		c.Assert(err, qt.IsNil)
+		sheetXML = string(b)
+	}
+	c.Assert(sheetXML, qt.Not(qt.Equals), "")
+	c.Assert(strings.Contains(sheetXML, "<sheetData>"), qt.Equals, true)
+	c.Assert(strings.Contains(sheetXML, `r="A1"`), qt.Equals, true)
+}
+
+// TestStreamWriterLargeSheet writes a million rows through StreamWriter to
+// exercise the bounded-memory streaming path: no Row/Cell objects or
+// CellStore entries are ever created for these rows, only XML fragments
+// appended to a temp file. Skipped under -short.
+func TestStreamWriterLargeSheet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 1M-row stress test in short mode")
+	}
+	c := qt.New(t)
+
+	file := NewFile()
+	sw, err := file.NewStreamWriter("Big")
+	c.Assert(err, qt.IsNil)
+	sw.InlineStrings = true
+
+	const rows = 1000000
+	for i := 0; i < rows; i++ {
+		axis := "A" + strconv.Itoa(i+1)
+		err := sw.SetRow(axis, []interface{}{i, "value-" + strconv.Itoa(i)})
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(sw.Flush(), qt.IsNil)
+}
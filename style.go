@@ -0,0 +1,86 @@
+package xlsx
+
+// Style collects the visual formatting applied to a Cell: border, fill,
+// font and alignment, plus the ApplyX flags the underlying XML uses to say
+// whether each group is actually in effect (as opposed to just present with
+// zero values).
+type Style struct {
+	Border    Border
+	Fill      Fill
+	Font      Font
+	Alignment Alignment
+
+	ApplyBorder    bool
+	ApplyFill      bool
+	ApplyFont      bool
+	ApplyAlignment bool
+}
+
+// Border describes the four edges of a cell's border, each as an OOXML
+// border style name (e.g. "thin") paired with its colour.
+type Border struct {
+	Left        string
+	LeftColor   string
+	Right       string
+	RightColor  string
+	Top         string
+	TopColor    string
+	Bottom      string
+	BottomColor string
+}
+
+// Fill describes a cell's background fill.
+type Fill struct {
+	PatternType string
+	BgColor     string
+	FgColor     string
+}
+
+// Font describes a cell's font.
+type Font struct {
+	Size      int
+	Name      string
+	Family    int
+	Charset   int
+	Color     string
+	Bold      bool
+	Italic    bool
+	Underline bool
+}
+
+// Alignment describes a cell's text alignment and wrapping behaviour.
+type Alignment struct {
+	Horizontal   string
+	Indent       int
+	ShrinkToFit  bool
+	TextRotation int
+	Vertical     string
+	WrapText     bool
+}
+
+// xlsxDataValidation mirrors the OOXML <dataValidation> element attached to
+// a Cell. The pointer fields are optional attributes that OOXML allows to be
+// absent entirely, as distinct from present-but-empty.
+type xlsxDataValidation struct {
+	AllowBlank       bool
+	ShowInputMessage bool
+	ShowErrorMessage bool
+	ErrorStyle       *string
+	ErrorTitle       *string
+	Operator         string
+	Error            *string
+	PromptTitle      *string
+	Prompt           *string
+	Type             string
+	Sqref            string
+	Formula1         string
+	Formula2         string
+}
+
+// Hyperlink describes a cell's hyperlink: the text OOXML shows for it, the
+// target it links to, and an optional tooltip.
+type Hyperlink struct {
+	DisplayString string
+	Link          string
+	Tooltip       string
+}